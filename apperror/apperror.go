@@ -0,0 +1,129 @@
+// Package apperror berisi error domain bertipe yang dipetakan secara terpusat ke HTTP status,
+// kode error yang stabil untuk klien, dan pesan untuk manusia.
+package apperror
+
+import "net/http"
+
+// Kind menentukan HTTP status sebuah Error
+type Kind string
+
+const (
+	KindValidation           Kind = "validation"
+	KindUnauthorized         Kind = "unauthorized"
+	KindForbidden            Kind = "forbidden"
+	KindNotFound             Kind = "not_found"
+	KindConflict             Kind = "conflict"
+	KindUnprocessable        Kind = "unprocessable"
+	KindPreconditionFailed   Kind = "precondition_failed"
+	KindPreconditionRequired Kind = "precondition_required"
+	KindInternal             Kind = "internal"
+)
+
+// Error adalah error domain. Code stabil dan aman dipakai klien untuk percabangan, Message untuk
+// ditampilkan, Details data tambahan opsional, Err penyebab asli yang hanya masuk log.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details any
+	Err     error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+// Unprocessable untuk request yang formatnya benar tapi isinya melanggar aturan validasi
+func Unprocessable(code, message string) *Error {
+	return New(KindUnprocessable, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+// PreconditionFailed untuk request bersyarat (If-Match) yang syaratnya tidak terpenuhi
+func PreconditionFailed(code, message string) *Error {
+	return New(KindPreconditionFailed, code, message)
+}
+
+// PreconditionRequired untuk request yang wajib bersyarat tapi tidak mengirim header syaratnya
+func PreconditionRequired(code, message string) *Error {
+	return New(KindPreconditionRequired, code, message)
+}
+
+// Internal membungkus error tak terduga; pesan penyebab tidak dikirim ke klien
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "Terjadi kesalahan pada server", Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is mencocokkan berdasarkan Kind dan Code, sehingga salinan dari WithDetails atau Wrap tetap
+// cocok dengan error sentinel asalnya lewat errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// WithDetails mengembalikan salinan error dengan data tambahan untuk klien
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// Wrap mengembalikan salinan error dengan penyebab asli untuk log
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+// Status adalah HTTP status untuk Kind error
+func (e *Error) Status() int {
+	switch e.Kind {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindUnprocessable:
+		return http.StatusUnprocessableEntity
+	case KindPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case KindPreconditionRequired:
+		return http.StatusPreconditionRequired
+	}
+	return http.StatusInternalServerError
+}
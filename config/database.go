@@ -5,31 +5,38 @@ import (
 	"log"
 	"os"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 func ConnectDB() {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_NAME"),
-	)
-
-	database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	driver := os.Getenv("DB_DRIVER")
+	if driver == "" {
+		driver = DriverMySQL
+	}
+
+	dialector, err := Dialector(driver)
+	if err != nil {
+		log.Fatal("Gagal terkoneksi ke database ", err)
+	}
 
+	database, err := OpenDB(dialector, logger.Info)
 	if err != nil {
 		log.Fatal("Gagal terkoneksi ke database ", err)
 	}
 	DB = database
-	fmt.Println("Terkoneksi dengan database ")
+	fmt.Printf("Terkoneksi dengan database (%s)\n", driver)
 
 	//Tes koneksi
 	sqlDB, err := database.DB()
@@ -42,3 +49,78 @@ func ConnectDB() {
 
 	fmt.Println("Database Ping success!")
 }
+
+// Dialector membuat dialector gorm sesuai DB_DRIVER dari variabel DB_* di environment.
+// DB_DSN, jika di-set, dipakai apa adanya untuk driver mana pun.
+func Dialector(driver string) (gorm.Dialector, error) {
+	dsn := os.Getenv("DB_DSN")
+
+	switch driver {
+	case DriverMySQL:
+		if dsn == "" {
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+				os.Getenv("DB_USER"),
+				os.Getenv("DB_PASSWORD"),
+				os.Getenv("DB_HOST"),
+				os.Getenv("DB_PORT"),
+				os.Getenv("DB_NAME"),
+			)
+		}
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		if dsn == "" {
+			sslMode := os.Getenv("DB_SSLMODE")
+			if sslMode == "" {
+				sslMode = "disable"
+			}
+			dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+				os.Getenv("DB_HOST"),
+				os.Getenv("DB_PORT"),
+				os.Getenv("DB_USER"),
+				os.Getenv("DB_PASSWORD"),
+				os.Getenv("DB_NAME"),
+				sslMode,
+			)
+		}
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		if dsn == "" {
+			path := os.Getenv("DB_NAME")
+			if path == "" {
+				path = "perpustakaan.db"
+			}
+			dsn = SQLiteDSN(path)
+		}
+		return sqlite.Open(dsn), nil
+	}
+
+	return nil, fmt.Errorf("DB_DRIVER %q tidak didukung, pilih mysql, postgres atau sqlite", driver)
+}
+
+// SQLiteDSN membuat DSN SQLite dengan foreign key aktif dan busy timeout supaya transaksi
+// yang berjalan bersamaan menunggu, bukan langsung gagal "database is locked"
+func SQLiteDSN(path string) string {
+	return path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+}
+
+// OpenDB membuka koneksi gorm. SQLite dibatasi satu koneksi karena hanya mendukung satu penulis.
+func OpenDB(dialector gorm.Dialector, logLevel logger.LogLevel) (*gorm.DB, error) {
+	// TranslateError menyeragamkan pelanggaran unique index semua driver menjadi gorm.ErrDuplicatedKey
+	database, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logLevel),
+		TranslateError: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dialector.Name() == DriverSQLite {
+		sqlDB, err := database.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	return database, nil
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"backend_perpustakaan_online/models"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"strconv"
 	"time"
@@ -14,57 +17,120 @@ type JWTConfig struct {
 	AdminSecretKey string
 	UserSecretKey  string
 	ExpiresIn      time.Duration
+	RefreshExpires time.Duration
 }
 
 var JWT *JWTConfig
 
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	TokenType string `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
-func InitJWT() {
+// MinSecretLength adalah panjang minimum secret JWT yang diterima di mode production
+const MinSecretLength = 32
+
+// InitJWT membaca konfigurasi JWT dari environment. Di mode production (APP_ENV=production)
+// semua secret wajib di-set, minimal MinSecretLength karakter dan admin/user harus berbeda.
+// Di luar production secret yang kosong diganti default development dengan peringatan di log.
+func InitJWT() error {
 	expiresIn, _ := strconv.Atoi(os.Getenv("JWT_EXPIRES_IN"))
 	if expiresIn == 0 {
 		expiresIn = 24
 	}
 
+	refreshExpiresIn, _ := strconv.Atoi(os.Getenv("JWT_REFRESH_EXPIRES_IN"))
+	if refreshExpiresIn == 0 {
+		refreshExpiresIn = 24 * 7
+	}
+
+	production := IsProduction()
+
+	secretKey, err := loadSecret("JWT_SECRET_KEY", "dev-default-secret-key", production)
+	if err != nil {
+		return err
+	}
+	adminSecret, err := loadSecret("JWT_ADMIN_SECRET_KEY", "dev-admin-secret-key", production)
+	if err != nil {
+		return err
+	}
+	userSecret, err := loadSecret("JWT_USER_SECRET_KEY", "dev-user-secret-key", production)
+	if err != nil {
+		return err
+	}
+
+	if adminSecret == userSecret {
+		return errors.New("JWT_ADMIN_SECRET_KEY dan JWT_USER_SECRET_KEY tidak boleh sama")
+	}
+
 	JWT = &JWTConfig{
-		SecretKey:      getEnv("JWT_SECRET_KEY", "default-secret-key"),
-		AdminSecretKey: getEnv("JWT_SECRET_KEY", "admin-secret-key"),
-		UserSecretKey:  getEnv("JWT_SECRET_KEY", "user-secret-key"),
+		SecretKey:      secretKey,
+		AdminSecretKey: adminSecret,
+		UserSecretKey:  userSecret,
 		ExpiresIn:      time.Hour * time.Duration(expiresIn),
+		RefreshExpires: time.Hour * time.Duration(refreshExpiresIn),
 	}
+	return nil
 }
 
-func getEnv(key, defaultValue string) string {
+func loadSecret(key, devDefault string, production bool) (string, error) {
 	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
+	switch {
+	case value == "" && production:
+		return "", fmt.Errorf("%s wajib di-set di mode production", key)
+	case value == "":
+		log.Printf("%s tidak di-set, memakai secret development default", key)
+		return devDefault, nil
+	case len(value) < MinSecretLength && production:
+		return "", fmt.Errorf("%s minimal %d karakter di mode production", key, MinSecretLength)
 	}
-	return value
+	return value, nil
+}
+
+// IsProduction mengembalikan true jika APP_ENV=production
+func IsProduction() bool {
+	return os.Getenv("APP_ENV") == "production"
 }
 
 func (j *JWTConfig) GenerateToken(userID uint, email string, role models.UserRole) (string, error) {
-	var secretKey string
+	return j.generate(userID, email, role, TokenTypeAccess, j.ExpiresIn)
+}
 
+// GenerateRefreshToken membuat token berumur panjang yang hanya bisa dipakai untuk /api/auth/refresh
+func (j *JWTConfig) GenerateRefreshToken(userID uint, email string, role models.UserRole) (string, error) {
+	return j.generate(userID, email, role, TokenTypeRefresh, j.RefreshExpires)
+}
+
+// secretFor memilih secret penandatangan sesuai role, sehingga token user tidak bisa lolos validasi admin
+func (j *JWTConfig) secretFor(role models.UserRole) string {
 	switch role {
 	case models.RoleAdmin:
-		secretKey = j.AdminSecretKey
+		return j.AdminSecretKey
 	case models.RoleUser:
-		secretKey = j.UserSecretKey
+		return j.UserSecretKey
 	default:
-		secretKey = j.SecretKey
+		return j.SecretKey
 	}
+}
+
+func (j *JWTConfig) generate(userID uint, email string, role models.UserRole, tokenType string, ttl time.Duration) (string, error) {
+	secretKey := j.secretFor(role)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   string(role),
+		UserID:    userID,
+		Email:     email,
+		Role:      string(role),
+		TokenType: tokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpiresIn)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -73,27 +139,19 @@ func (j *JWTConfig) GenerateToken(userID uint, email string, role models.UserRol
 	return token.SignedString([]byte(secretKey))
 }
 
+// ValidateToken memvalidasi token dengan secret milik role dan memastikan klaim role cocok dengan secret tersebut
 func (j *JWTConfig) ValidateToken(tokenString string, role models.UserRole) (*Claims, error) {
-	var secretKey string
-
-	switch role {
-	case models.RoleAdmin:
-		secretKey = j.AdminSecretKey
-	case models.RoleUser:
-		secretKey = j.UserSecretKey
-	default:
-		secretKey = j.SecretKey
-	}
+	secretKey := j.secretFor(role)
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secretKey), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.Role == string(role) {
 		return claims, nil
 	}
 
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"backend_perpustakaan_online/models"
+	"os"
+	"strconv"
+	"time"
+)
+
+type LibraryConfig struct {
+	LoanPeriod       time.Duration
+	MaxRenewals      int
+	HoldPickupWindow time.Duration
+	FinePolicy       models.FinePolicy
+	// FineBlockThreshold adalah total denda maksimum sebelum member tidak boleh meminjam lagi
+	FineBlockThreshold int64
+	// TrashRetention adalah umur minimum book di trash sebelum boleh di-purge
+	TrashRetention time.Duration
+}
+
+var Library *LibraryConfig
+
+// InitLibrary membaca aturan sirkulasi perpustakaan dari environment
+func InitLibrary() {
+	loanDays, _ := strconv.Atoi(os.Getenv("LOAN_PERIOD_DAYS"))
+	if loanDays <= 0 {
+		loanDays = 14
+	}
+
+	holdPickupDays, _ := strconv.Atoi(os.Getenv("HOLD_PICKUP_DAYS"))
+	if holdPickupDays <= 0 {
+		holdPickupDays = 3
+	}
+
+	Library = &LibraryConfig{
+		LoanPeriod:       time.Hour * 24 * time.Duration(loanDays),
+		MaxRenewals:      int(getEnvInt64("LOAN_MAX_RENEWALS", 2)),
+		HoldPickupWindow: time.Hour * 24 * time.Duration(holdPickupDays),
+		FinePolicy: models.FinePolicy{
+			DailyRate: getEnvInt64("FINE_DAILY_RATE", 1000),
+			MaxAmount: getEnvInt64("FINE_MAX_AMOUNT", 50000),
+		},
+		FineBlockThreshold: getEnvInt64("FINE_BLOCK_THRESHOLD", 20000),
+		TrashRetention:     time.Hour * 24 * time.Duration(getEnvInt64("BOOK_TRASH_RETENTION_DAYS", 30)),
+	}
+}
+
+func getEnvInt64(key string, defaultValue int64) int64 {
+	value, err := strconv.ParseInt(os.Getenv(key), 10, 64)
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
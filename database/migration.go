@@ -3,28 +3,80 @@ package database
 import (
 	"backend_perpustakaan_online/config"
 	"backend_perpustakaan_online/models"
+	"fmt"
 	"log"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// Migrate menjalankan semua migration yang masih pending saat server start
 func Migrate() {
-	err := config.DB.AutoMigrate(
-		&models.Book{},
-	)
+	migrator, err := NewMigrator(config.DB)
+	if err != nil {
+		log.Fatal("Gagal migrasi ke database ", err)
+	}
 
+	migrations, err := migrator.Up()
 	if err != nil {
-		log.Fatal("Gagal migrasi ke database", err)
+		log.Fatal("Gagal migrasi ke database ", err)
 	}
 
+	for _, migration := range migrations {
+		log.Printf("Migration %d_%s dijalankan", migration.Version, migration.Name)
+	}
 	log.Println("Database migrasi berhasil")
 }
 
-func Seeder() {
-	books := []models.Book{
+// backfillBookCopies membuat satu eksemplar untuk setiap book yang belum punya eksemplar,
+// mewarisi status book, lalu menautkan loan aktif ke eksemplar tersebut. Dipakai Seeder;
+// database lama di-backfill oleh migration 0002_backfill_book_copies.
+func backfillBookCopies() error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		var books []models.Book
+		err := tx.Where("id NOT IN (?)", tx.Model(&models.BookCopy{}).Select("book_id")).Find(&books).Error
+		if err != nil {
+			return err
+		}
+
+		for _, book := range books {
+			status := book.Status
+			if status == "" {
+				status = models.CopyStatusAvailable
+			}
+
+			bookCopy := models.BookCopy{
+				BookID:    book.ID,
+				Barcode:   fmt.Sprintf("B%06d-%03d", book.ID, 1),
+				Condition: models.CopyConditionGood,
+				Status:    status,
+			}
+			if err := tx.Create(&bookCopy).Error; err != nil {
+				return err
+			}
+
+			err := tx.Model(&models.Loan{}).
+				Where("book_id = ? AND copy_id IS NULL AND returned_at IS NULL", book.ID).
+				Update("copy_id", bookCopy.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(books) > 0 {
+			log.Printf("Backfill %d eksemplar buku", len(books))
+		}
+		return nil
+	})
+}
+
+// SampleBooks adalah data contoh untuk Seeder dan demo mode
+func SampleBooks() []models.Book {
+	return []models.Book{
 		{
 			Title:       "The Great Gatsby",
 			Author:      "F. Scott Fitzgerald",
-			ISBN:        "978-0743273565",
+			ISBN:        "9780743273565",
 			Description: "A classic novel of the Jazz Age",
 			Category:    "Fiction",
 			TotalPages:  180,
@@ -35,7 +87,7 @@ func Seeder() {
 		{
 			Title:       "To Kill a Mockingbird",
 			Author:      "Harper Lee",
-			ISBN:        "978-0061120084",
+			ISBN:        "9780061120084",
 			Description: "A novel about racial inequality",
 			Category:    "Fiction",
 			TotalPages:  281,
@@ -46,7 +98,7 @@ func Seeder() {
 		{
 			Title:       "1984",
 			Author:      "George Orwell",
-			ISBN:        "978-0452284234",
+			ISBN:        "9780452284234",
 			Description: "Dystopian social science fiction",
 			Category:    "Science Fiction",
 			TotalPages:  328,
@@ -55,8 +107,10 @@ func Seeder() {
 			Status:      "borrowed",
 		},
 	}
+}
 
-	for _, book := range books {
+func Seeder() {
+	for _, book := range SampleBooks() {
 		var existingBook models.Book
 		if err := config.DB.Where("isbn = ?", book.ISBN).First(&existingBook).Error; err != nil {
 			if err := config.DB.Create(&book).Error; err != nil {
@@ -65,6 +119,10 @@ func Seeder() {
 		}
 	}
 
+	if err := backfillBookCopies(); err != nil {
+		log.Printf("Failed to create book copies: %v", err)
+	}
+
 	log.Println("Database seeding completed!")
 }
 
@@ -0,0 +1,144 @@
+-- Skema awal, sama dengan hasil AutoMigrate sebelumnya. IF NOT EXISTS supaya database lama
+-- yang dibuat lewat AutoMigrate bisa langsung ditandai sudah migrasi.
+
+CREATE TABLE IF NOT EXISTS `books` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `title` varchar(255) NOT NULL,
+    `author` varchar(255) NOT NULL,
+    `isbn` varchar(20) NOT NULL,
+    `description` text,
+    `category` varchar(100),
+    `total_pages` bigint,
+    `publisher` varchar(255),
+    `publisher_at` date,
+    `status` varchar(20) DEFAULT 'available',
+    `created_at` datetime(3) NULL,
+    `update_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_books_isbn` (`isbn`),
+    INDEX `idx_books_deleted_at` (`deleted_at`),
+    CONSTRAINT `chk_books_status` CHECK (status IN ('available','borrowed','maintenance'))
+);
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `name` varchar(255) NOT NULL,
+    `email` varchar(255) NOT NULL,
+    `password` varchar(255) NOT NULL,
+    `role` varchar(20) DEFAULT 'user',
+    `is_active` boolean DEFAULT true,
+    `last_login` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_users_email` (`email`),
+    INDEX `idx_users_deleted_at` (`deleted_at`),
+    CONSTRAINT `chk_users_role` CHECK (role IN ('admin','user'))
+);
+
+CREATE TABLE IF NOT EXISTS `book_copies` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` bigint unsigned NOT NULL,
+    `barcode` varchar(50) NOT NULL,
+    `shelf_location` varchar(100),
+    `condition` varchar(20) DEFAULT 'good',
+    `status` varchar(20) DEFAULT 'available',
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_book_copies_book_id` (`book_id`),
+    UNIQUE INDEX `idx_book_copies_barcode` (`barcode`),
+    INDEX `idx_book_copies_status` (`status`),
+    CONSTRAINT `fk_book_copies_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_book_copies_status` CHECK (status IN ('available','borrowed','maintenance','lost','on_hold'))
+);
+
+CREATE TABLE IF NOT EXISTS `loans` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `book_id` bigint unsigned NOT NULL,
+    `copy_id` bigint unsigned,
+    `borrowed_at` datetime(3) NOT NULL,
+    `due_at` datetime(3) NOT NULL,
+    `returned_at` datetime(3) NULL,
+    `renewal_count` bigint NOT NULL DEFAULT 0,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_loans_user_id` (`user_id`),
+    INDEX `idx_loans_book_id` (`book_id`),
+    INDEX `idx_loans_copy_id` (`copy_id`),
+    INDEX `idx_loans_returned_at` (`returned_at`),
+    CONSTRAINT `fk_loans_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_loans_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_loans_copy` FOREIGN KEY (`copy_id`) REFERENCES `book_copies`(`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS `loan_renewals` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `loan_id` bigint unsigned NOT NULL,
+    `previous_due_at` datetime(3) NOT NULL,
+    `new_due_at` datetime(3) NOT NULL,
+    `renewed_by_id` bigint unsigned NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_loan_renewals_loan_id` (`loan_id`),
+    CONSTRAINT `fk_loan_renewals_loan` FOREIGN KEY (`loan_id`) REFERENCES `loans`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS `holds` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `book_id` bigint unsigned NOT NULL,
+    `user_id` bigint unsigned NOT NULL,
+    `status` varchar(20) DEFAULT 'waiting',
+    `copy_id` bigint unsigned,
+    `ready_at` datetime(3) NULL,
+    `expires_at` datetime(3) NULL,
+    `closed_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_holds_book_status` (`book_id`,`status`),
+    INDEX `idx_holds_user_id` (`user_id`),
+    INDEX `idx_holds_expires_at` (`expires_at`),
+    CONSTRAINT `fk_holds_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_holds_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_holds_copy` FOREIGN KEY (`copy_id`) REFERENCES `book_copies`(`id`) ON DELETE SET NULL,
+    CONSTRAINT `chk_holds_status` CHECK (status IN ('waiting','ready','fulfilled','cancelled','expired'))
+);
+
+CREATE TABLE IF NOT EXISTS `fines` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `user_id` bigint unsigned NOT NULL,
+    `loan_id` bigint unsigned NOT NULL,
+    `days_overdue` bigint NOT NULL,
+    `amount` bigint NOT NULL,
+    `paid_amount` bigint NOT NULL DEFAULT 0,
+    `waived_amount` bigint NOT NULL DEFAULT 0,
+    `status` varchar(20) DEFAULT 'outstanding',
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_fines_user_id` (`user_id`),
+    UNIQUE INDEX `idx_fines_loan_id` (`loan_id`),
+    INDEX `idx_fines_status` (`status`),
+    CONSTRAINT `fk_fines_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_fines_loan` FOREIGN KEY (`loan_id`) REFERENCES `loans`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `chk_fines_status` CHECK (status IN ('outstanding','paid','waived'))
+);
+
+CREATE TABLE IF NOT EXISTS `fine_transactions` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `fine_id` bigint unsigned NOT NULL,
+    `type` varchar(20) NOT NULL,
+    `amount` bigint NOT NULL,
+    `note` varchar(255),
+    `recorded_by_id` bigint unsigned NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_fine_transactions_fine_id` (`fine_id`),
+    CONSTRAINT `fk_fine_transactions_fine` FOREIGN KEY (`fine_id`) REFERENCES `fines`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_fine_transactions_type` CHECK (type IN ('payment','waiver'))
+);
@@ -0,0 +1 @@
+-- Normalisasi ISBN tidak di-rollback: bentuk asli sebelum dinormalisasi tidak disimpan.
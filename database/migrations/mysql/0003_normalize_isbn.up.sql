@@ -0,0 +1,33 @@
+-- ISBN disimpan dalam bentuk kanonik: ISBN-13 tanpa tanda hubung dan spasi. Jika ada dua book
+-- dengan ISBN yang sama dalam format berbeda, migration gagal karena unique index dan duplikatnya
+-- perlu digabung manual lebih dulu
+UPDATE books SET isbn = REPLACE(REPLACE(UPPER(isbn), '-', ''), ' ', '');
+
+-- ISBN-10 dengan check digit benar dikonversi ke ISBN-13: prefix 978, sembilan digit pertama, lalu
+-- check digit baru. Prefix 978 menyumbang 9*1 + 7*3 + 8*1 = 38 ke jumlah berbobot ISBN-13.
+-- Checksum dihitung di dalam CASE supaya CAST hanya dijalankan pada baris yang formatnya cocok.
+UPDATE books
+SET isbn = CONCAT('978', SUBSTR(isbn, 1, 9), (10 - (38
+        + 3 * CAST(SUBSTR(isbn, 1, 1) AS UNSIGNED)
+        + CAST(SUBSTR(isbn, 2, 1) AS UNSIGNED)
+        + 3 * CAST(SUBSTR(isbn, 3, 1) AS UNSIGNED)
+        + CAST(SUBSTR(isbn, 4, 1) AS UNSIGNED)
+        + 3 * CAST(SUBSTR(isbn, 5, 1) AS UNSIGNED)
+        + CAST(SUBSTR(isbn, 6, 1) AS UNSIGNED)
+        + 3 * CAST(SUBSTR(isbn, 7, 1) AS UNSIGNED)
+        + CAST(SUBSTR(isbn, 8, 1) AS UNSIGNED)
+        + 3 * CAST(SUBSTR(isbn, 9, 1) AS UNSIGNED)
+    ) % 10) % 10)
+WHERE LENGTH(isbn) = 10
+  AND CASE WHEN isbn REGEXP '^[0-9]{9}[0-9X]$' THEN (
+          10 * CAST(SUBSTR(isbn, 1, 1) AS UNSIGNED)
+        + 9 * CAST(SUBSTR(isbn, 2, 1) AS UNSIGNED)
+        + 8 * CAST(SUBSTR(isbn, 3, 1) AS UNSIGNED)
+        + 7 * CAST(SUBSTR(isbn, 4, 1) AS UNSIGNED)
+        + 6 * CAST(SUBSTR(isbn, 5, 1) AS UNSIGNED)
+        + 5 * CAST(SUBSTR(isbn, 6, 1) AS UNSIGNED)
+        + 4 * CAST(SUBSTR(isbn, 7, 1) AS UNSIGNED)
+        + 3 * CAST(SUBSTR(isbn, 8, 1) AS UNSIGNED)
+        + 2 * CAST(SUBSTR(isbn, 9, 1) AS UNSIGNED)
+        + CASE SUBSTR(isbn, 10, 1) WHEN 'X' THEN 10 ELSE CAST(SUBSTR(isbn, 10, 1) AS UNSIGNED) END
+    ) % 11 = 0 ELSE FALSE END;
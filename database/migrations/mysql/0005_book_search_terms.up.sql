@@ -0,0 +1,12 @@
+-- Inverted index untuk pencarian katalog. Diisi oleh aplikasi karena tokenisasi dan pembobotan
+-- dilakukan di Go; book lama diindeks saat server start.
+-- term memakai collation biner karena sudah dinormalkan di Go dan harus dibandingkan persis.
+CREATE TABLE IF NOT EXISTS `book_search_terms` (
+    `book_id` bigint unsigned NOT NULL,
+    `field` varchar(20) NOT NULL,
+    `term` varchar(64) COLLATE utf8mb4_bin NOT NULL,
+    `frequency` bigint NOT NULL,
+    PRIMARY KEY (`book_id`, `field`, `term`),
+    INDEX `idx_book_search_terms_term` (`term`),
+    CONSTRAINT `fk_book_search_terms_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE CASCADE
+);
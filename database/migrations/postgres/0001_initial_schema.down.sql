@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS "fine_transactions";
+DROP TABLE IF EXISTS "fines";
+DROP TABLE IF EXISTS "holds";
+DROP TABLE IF EXISTS "loan_renewals";
+DROP TABLE IF EXISTS "loans";
+DROP TABLE IF EXISTS "book_copies";
+DROP TABLE IF EXISTS "users";
+DROP TABLE IF EXISTS "books";
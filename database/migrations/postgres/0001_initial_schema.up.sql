@@ -0,0 +1,145 @@
+-- Skema awal, sama dengan hasil AutoMigrate sebelumnya. IF NOT EXISTS supaya database lama
+-- yang dibuat lewat AutoMigrate bisa langsung ditandai sudah migrasi.
+
+CREATE TABLE IF NOT EXISTS "books" (
+    "id" bigserial,
+    "title" varchar(255) NOT NULL,
+    "author" varchar(255) NOT NULL,
+    "isbn" varchar(20) NOT NULL,
+    "description" text,
+    "category" varchar(100),
+    "total_pages" bigint,
+    "publisher" varchar(255),
+    "publisher_at" date,
+    "status" varchar(20) DEFAULT 'available',
+    "created_at" timestamptz,
+    "update_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "chk_books_status" CHECK (status IN ('available','borrowed','maintenance'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_books_isbn" ON "books" ("isbn");
+CREATE INDEX IF NOT EXISTS "idx_books_deleted_at" ON "books" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" bigserial,
+    "name" varchar(255) NOT NULL,
+    "email" varchar(255) NOT NULL,
+    "password" varchar(255) NOT NULL,
+    "role" varchar(20) DEFAULT 'user',
+    "is_active" boolean DEFAULT true,
+    "last_login" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "chk_users_role" CHECK (role IN ('admin','user'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "book_copies" (
+    "id" bigserial,
+    "book_id" bigint NOT NULL,
+    "barcode" varchar(50) NOT NULL,
+    "shelf_location" varchar(100),
+    "condition" varchar(20) DEFAULT 'good',
+    "status" varchar(20) DEFAULT 'available',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_book_copies_book" FOREIGN KEY ("book_id") REFERENCES "books"("id") ON DELETE CASCADE,
+    CONSTRAINT "chk_book_copies_status" CHECK (status IN ('available','borrowed','maintenance','lost','on_hold'))
+);
+CREATE INDEX IF NOT EXISTS "idx_book_copies_book_id" ON "book_copies" ("book_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_book_copies_barcode" ON "book_copies" ("barcode");
+CREATE INDEX IF NOT EXISTS "idx_book_copies_status" ON "book_copies" ("status");
+
+CREATE TABLE IF NOT EXISTS "loans" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "book_id" bigint NOT NULL,
+    "copy_id" bigint,
+    "borrowed_at" timestamptz NOT NULL,
+    "due_at" timestamptz NOT NULL,
+    "returned_at" timestamptz,
+    "renewal_count" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_loans_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_loans_book" FOREIGN KEY ("book_id") REFERENCES "books"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_loans_copy" FOREIGN KEY ("copy_id") REFERENCES "book_copies"("id") ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS "idx_loans_user_id" ON "loans" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_loans_book_id" ON "loans" ("book_id");
+CREATE INDEX IF NOT EXISTS "idx_loans_copy_id" ON "loans" ("copy_id");
+CREATE INDEX IF NOT EXISTS "idx_loans_returned_at" ON "loans" ("returned_at");
+
+CREATE TABLE IF NOT EXISTS "loan_renewals" (
+    "id" bigserial,
+    "loan_id" bigint NOT NULL,
+    "previous_due_at" timestamptz NOT NULL,
+    "new_due_at" timestamptz NOT NULL,
+    "renewed_by_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_loan_renewals_loan" FOREIGN KEY ("loan_id") REFERENCES "loans"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_loan_renewals_loan_id" ON "loan_renewals" ("loan_id");
+
+CREATE TABLE IF NOT EXISTS "holds" (
+    "id" bigserial,
+    "book_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "status" varchar(20) DEFAULT 'waiting',
+    "copy_id" bigint,
+    "ready_at" timestamptz,
+    "expires_at" timestamptz,
+    "closed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_holds_book" FOREIGN KEY ("book_id") REFERENCES "books"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_holds_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_holds_copy" FOREIGN KEY ("copy_id") REFERENCES "book_copies"("id") ON DELETE SET NULL,
+    CONSTRAINT "chk_holds_status" CHECK (status IN ('waiting','ready','fulfilled','cancelled','expired'))
+);
+CREATE INDEX IF NOT EXISTS "idx_holds_book_status" ON "holds" ("book_id","status");
+CREATE INDEX IF NOT EXISTS "idx_holds_user_id" ON "holds" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_holds_expires_at" ON "holds" ("expires_at");
+
+CREATE TABLE IF NOT EXISTS "fines" (
+    "id" bigserial,
+    "user_id" bigint NOT NULL,
+    "loan_id" bigint NOT NULL,
+    "days_overdue" bigint NOT NULL,
+    "amount" bigint NOT NULL,
+    "paid_amount" bigint NOT NULL DEFAULT 0,
+    "waived_amount" bigint NOT NULL DEFAULT 0,
+    "status" varchar(20) DEFAULT 'outstanding',
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_fines_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE RESTRICT,
+    CONSTRAINT "fk_fines_loan" FOREIGN KEY ("loan_id") REFERENCES "loans"("id") ON DELETE RESTRICT,
+    CONSTRAINT "chk_fines_status" CHECK (status IN ('outstanding','paid','waived'))
+);
+CREATE INDEX IF NOT EXISTS "idx_fines_user_id" ON "fines" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_fines_loan_id" ON "fines" ("loan_id");
+CREATE INDEX IF NOT EXISTS "idx_fines_status" ON "fines" ("status");
+
+CREATE TABLE IF NOT EXISTS "fine_transactions" (
+    "id" bigserial,
+    "fine_id" bigint NOT NULL,
+    "type" varchar(20) NOT NULL,
+    "amount" bigint NOT NULL,
+    "note" varchar(255),
+    "recorded_by_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_fine_transactions_fine" FOREIGN KEY ("fine_id") REFERENCES "fines"("id") ON DELETE CASCADE,
+    CONSTRAINT "chk_fine_transactions_type" CHECK (type IN ('payment','waiver'))
+);
+
+CREATE INDEX IF NOT EXISTS "idx_fine_transactions_fine_id" ON "fine_transactions" ("fine_id");
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- Sebelum soft delete aktif, deleted_at selalu berisi zero time; tanpa dibersihkan semua book
+-- lama akan dianggap sudah di-trash
+UPDATE books SET deleted_at = NULL WHERE deleted_at < '1000-01-01';
+
+-- Satu eksemplar untuk setiap book lama yang belum punya eksemplar, mewarisi status book
+INSERT INTO book_copies (book_id, barcode, "condition", status, created_at, updated_at)
+SELECT id, 'B' || LPAD(id::text, 6, '0') || '-001', 'good', COALESCE(NULLIF(status, ''), 'available'), NOW(), NOW()
+FROM books
+WHERE id NOT IN (SELECT book_id FROM book_copies);
+
+-- Loan aktif dari sebelum ada eksemplar ditautkan ke eksemplar hasil backfill
+UPDATE loans
+SET copy_id = (SELECT MIN(book_copies.id) FROM book_copies WHERE book_copies.book_id = loans.book_id)
+WHERE copy_id IS NULL AND returned_at IS NULL;
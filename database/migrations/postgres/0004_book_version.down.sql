@@ -0,0 +1,2 @@
+ALTER TABLE "books" DROP COLUMN "version";
+ALTER TABLE "books" RENAME COLUMN "updated_at" TO "update_at";
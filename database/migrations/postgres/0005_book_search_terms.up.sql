@@ -0,0 +1,11 @@
+-- Inverted index untuk pencarian katalog. Diisi oleh aplikasi karena tokenisasi dan pembobotan
+-- dilakukan di Go; book lama diindeks saat server start.
+CREATE TABLE IF NOT EXISTS "book_search_terms" (
+    "book_id" bigint NOT NULL,
+    "field" varchar(20) NOT NULL,
+    "term" varchar(64) NOT NULL,
+    "frequency" bigint NOT NULL,
+    PRIMARY KEY ("book_id", "field", "term"),
+    CONSTRAINT "fk_book_search_terms_book" FOREIGN KEY ("book_id") REFERENCES "books"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_book_search_terms_term" ON "book_search_terms" ("term");
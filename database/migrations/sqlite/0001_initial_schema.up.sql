@@ -0,0 +1,137 @@
+-- Skema awal, sama dengan hasil AutoMigrate sebelumnya. IF NOT EXISTS supaya database lama
+-- yang dibuat lewat AutoMigrate bisa langsung ditandai sudah migrasi.
+
+CREATE TABLE IF NOT EXISTS `books` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `title` varchar(255) NOT NULL,
+    `author` varchar(255) NOT NULL,
+    `isbn` varchar(20) NOT NULL,
+    `description` text,
+    `category` varchar(100),
+    `total_pages` integer,
+    `publisher` varchar(255),
+    `publisher_at` date,
+    `status` varchar(20) DEFAULT 'available',
+    `created_at` datetime,
+    `update_at` datetime,
+    `deleted_at` datetime,
+    CONSTRAINT `chk_books_status` CHECK (status IN ('available','borrowed','maintenance'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_books_isbn` ON `books` (`isbn`);
+CREATE INDEX IF NOT EXISTS `idx_books_deleted_at` ON `books` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `users` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `name` varchar(255) NOT NULL,
+    `email` varchar(255) NOT NULL,
+    `password` varchar(255) NOT NULL,
+    `role` varchar(20) DEFAULT 'user',
+    `is_active` numeric DEFAULT true,
+    `last_login` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    CONSTRAINT `chk_users_role` CHECK (role IN ('admin','user'))
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_email` ON `users` (`email`);
+CREATE INDEX IF NOT EXISTS `idx_users_deleted_at` ON `users` (`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `book_copies` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `book_id` integer NOT NULL,
+    `barcode` varchar(50) NOT NULL,
+    `shelf_location` varchar(100),
+    `condition` varchar(20) DEFAULT 'good',
+    `status` varchar(20) DEFAULT 'available',
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_book_copies_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_book_copies_status` CHECK (status IN ('available','borrowed','maintenance','lost','on_hold'))
+);
+CREATE INDEX IF NOT EXISTS `idx_book_copies_book_id` ON `book_copies` (`book_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_book_copies_barcode` ON `book_copies` (`barcode`);
+CREATE INDEX IF NOT EXISTS `idx_book_copies_status` ON `book_copies` (`status`);
+
+CREATE TABLE IF NOT EXISTS `loans` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `book_id` integer NOT NULL,
+    `copy_id` integer,
+    `borrowed_at` datetime NOT NULL,
+    `due_at` datetime NOT NULL,
+    `returned_at` datetime,
+    `renewal_count` integer NOT NULL DEFAULT 0,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_loans_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_loans_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_loans_copy` FOREIGN KEY (`copy_id`) REFERENCES `book_copies`(`id`) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS `idx_loans_user_id` ON `loans` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_loans_book_id` ON `loans` (`book_id`);
+CREATE INDEX IF NOT EXISTS `idx_loans_copy_id` ON `loans` (`copy_id`);
+CREATE INDEX IF NOT EXISTS `idx_loans_returned_at` ON `loans` (`returned_at`);
+
+CREATE TABLE IF NOT EXISTS `loan_renewals` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `loan_id` integer NOT NULL,
+    `previous_due_at` datetime NOT NULL,
+    `new_due_at` datetime NOT NULL,
+    `renewed_by_id` integer NOT NULL,
+    `created_at` datetime,
+    CONSTRAINT `fk_loan_renewals_loan` FOREIGN KEY (`loan_id`) REFERENCES `loans`(`id`) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS `idx_loan_renewals_loan_id` ON `loan_renewals` (`loan_id`);
+
+CREATE TABLE IF NOT EXISTS `holds` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `book_id` integer NOT NULL,
+    `user_id` integer NOT NULL,
+    `status` varchar(20) DEFAULT 'waiting',
+    `copy_id` integer,
+    `ready_at` datetime,
+    `expires_at` datetime,
+    `closed_at` datetime,
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_holds_book` FOREIGN KEY (`book_id`) REFERENCES `books`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_holds_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `fk_holds_copy` FOREIGN KEY (`copy_id`) REFERENCES `book_copies`(`id`) ON DELETE SET NULL,
+    CONSTRAINT `chk_holds_status` CHECK (status IN ('waiting','ready','fulfilled','cancelled','expired'))
+);
+CREATE INDEX IF NOT EXISTS `idx_holds_book_status` ON `holds` (`book_id`,`status`);
+CREATE INDEX IF NOT EXISTS `idx_holds_user_id` ON `holds` (`user_id`);
+CREATE INDEX IF NOT EXISTS `idx_holds_expires_at` ON `holds` (`expires_at`);
+
+CREATE TABLE IF NOT EXISTS `fines` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `user_id` integer NOT NULL,
+    `loan_id` integer NOT NULL,
+    `days_overdue` integer NOT NULL,
+    `amount` integer NOT NULL,
+    `paid_amount` integer NOT NULL DEFAULT 0,
+    `waived_amount` integer NOT NULL DEFAULT 0,
+    `status` varchar(20) DEFAULT 'outstanding',
+    `created_at` datetime,
+    `updated_at` datetime,
+    CONSTRAINT `fk_fines_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `fk_fines_loan` FOREIGN KEY (`loan_id`) REFERENCES `loans`(`id`) ON DELETE RESTRICT,
+    CONSTRAINT `chk_fines_status` CHECK (status IN ('outstanding','paid','waived'))
+);
+CREATE INDEX IF NOT EXISTS `idx_fines_user_id` ON `fines` (`user_id`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_fines_loan_id` ON `fines` (`loan_id`);
+CREATE INDEX IF NOT EXISTS `idx_fines_status` ON `fines` (`status`);
+
+CREATE TABLE IF NOT EXISTS `fine_transactions` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `fine_id` integer NOT NULL,
+    `type` varchar(20) NOT NULL,
+    `amount` integer NOT NULL,
+    `note` varchar(255),
+    `recorded_by_id` integer NOT NULL,
+    `created_at` datetime,
+    CONSTRAINT `fk_fine_transactions_fine` FOREIGN KEY (`fine_id`) REFERENCES `fines`(`id`) ON DELETE CASCADE,
+    CONSTRAINT `chk_fine_transactions_type` CHECK (type IN ('payment','waiver'))
+);
+
+CREATE INDEX IF NOT EXISTS `idx_fine_transactions_fine_id` ON `fine_transactions` (`fine_id`);
\ No newline at end of file
@@ -0,0 +1 @@
+-- Backfill data tidak di-rollback: eksemplar yang dibuat sudah bisa dipinjam dan dipakai loan.
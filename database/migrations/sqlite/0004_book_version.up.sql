@@ -0,0 +1,7 @@
+-- Kolom update_at diganti nama menjadi updated_at supaya diisi otomatis oleh GORM. Sebelumnya
+-- kolom ini tidak pernah terisi, jadi book lama memakai created_at.
+ALTER TABLE `books` RENAME COLUMN `update_at` TO `updated_at`;
+UPDATE `books` SET `updated_at` = `created_at` WHERE `updated_at` IS NULL OR `updated_at` < '1000-01-01';
+
+-- version naik setiap kali book berubah, dipakai sebagai ETag dan untuk optimistic locking
+ALTER TABLE `books` ADD COLUMN `version` integer NOT NULL DEFAULT 1;
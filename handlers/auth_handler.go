@@ -1 +1,171 @@
 package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"backend_perpustakaan_online/validation"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errInvalidCredentials  = apperror.Unauthorized("invalid_credentials", "Email atau password salah")
+	errInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "Refresh token tidak valid atau sudah kedaluwarsa")
+)
+
+type AuthHandler struct {
+	userRepo *repositories.UserRepository
+}
+
+func NewAuthHandler(userRepo *repositories.UserRepository) *AuthHandler {
+	return &AuthHandler{
+		userRepo: userRepo,
+	}
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func (h *AuthHandler) Register(c *fiber.Ctx) error {
+	var userReq models.UserRequest
+	if err := c.BodyParser(&userReq); err != nil {
+		return errInvalidBody
+	}
+
+	userReq.Email = strings.ToLower(strings.TrimSpace(userReq.Email))
+	if err := validation.Struct(&userReq); err != nil {
+		return err
+	}
+
+	exists, err := h.userRepo.CheckEmailExists(userReq.Email, 0)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return repositories.ErrEmailExists
+	}
+
+	// Registrasi publik selalu membuat member biasa, admin dibuat lewat /api/users
+	user := models.User{
+		Name:     userReq.Name,
+		Email:    userReq.Email,
+		Password: userReq.Password,
+		Role:     models.RoleUser,
+		IsActive: true,
+	}
+
+	if err := h.userRepo.Create(&user); err != nil {
+		return err
+	}
+
+	return h.respondWithTokens(c, fiber.StatusCreated, &user)
+}
+
+func (h *AuthHandler) Login(c *fiber.Ctx) error {
+	var loginReq models.LoginRequest
+	if err := c.BodyParser(&loginReq); err != nil {
+		return errInvalidBody
+	}
+
+	loginReq.Email = strings.ToLower(strings.TrimSpace(loginReq.Email))
+	if err := validation.Struct(&loginReq); err != nil {
+		return err
+	}
+
+	// Hanya email yang tidak terdaftar yang dianggap kredensial salah; error lain, misalnya database
+	// mati, diteruskan supaya tercatat sebagai 500
+	user, err := h.userRepo.GetByEmail(loginReq.Email)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return errInvalidCredentials
+	}
+	if err != nil {
+		return err
+	}
+	if !user.CheckPassword(loginReq.Password) {
+		return errInvalidCredentials
+	}
+
+	if !user.IsActive {
+		return middleware.ErrAccountInactive
+	}
+
+	now := time.Now()
+	if err := h.userRepo.UpdateLastLogin(user.ID, now); err != nil {
+		return err
+	}
+	user.LastLogin = &now
+
+	return h.respondWithTokens(c, fiber.StatusOK, user)
+}
+
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var request refreshRequest
+	if err := c.BodyParser(&request); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Struct(&request); err != nil {
+		return err
+	}
+
+	claims, err := config.JWT.ValidateAnyToken(request.RefreshToken)
+	if err != nil || claims.TokenType != config.TokenTypeRefresh {
+		return errInvalidRefreshToken
+	}
+
+	// Ambil ulang user supaya perubahan role atau deaktivasi langsung berlaku; seperti Login, hanya
+	// user yang tidak ditemukan yang membuat token tidak valid
+	user, err := h.userRepo.GetByID(claims.UserID)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return errInvalidRefreshToken
+	}
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return errInvalidRefreshToken
+	}
+
+	return h.respondWithTokens(c, fiber.StatusOK, user)
+}
+
+func (h *AuthHandler) respondWithTokens(c *fiber.Ctx, status int, user *models.User) error {
+	accessToken, err := config.JWT.GenerateToken(user.ID, user.Email, user.Role)
+	if err != nil {
+		return err
+	}
+
+	refreshToken, err := config.JWT.GenerateRefreshToken(user.ID, user.Email, user.Role)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": true,
+		"data": fiber.Map{
+			"user":          newUserResponse(user),
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+			"token_type":    "Bearer",
+			"expires_in":    int(config.JWT.ExpiresIn.Seconds()),
+		},
+	})
+}
+
+func newUserResponse(user *models.User) models.UserResponse {
+	return models.UserResponse{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Role:      user.Role,
+		IsActive:  user.IsActive,
+		LastLogin: user.LastLogin,
+		CreatedAt: user.CreatedAt,
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"backend_perpustakaan_online/validation"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BookCopyHandler struct {
+	books    repositories.BookStore
+	copyRepo *repositories.BookCopyRepository
+}
+
+func NewBookCopyHandler(books repositories.BookStore, copyRepo *repositories.BookCopyRepository) *BookCopyHandler {
+	return &BookCopyHandler{
+		books:    books,
+		copyRepo: copyRepo,
+	}
+}
+
+func (h *BookCopyHandler) GetBookCopies(c *fiber.Ctx) error {
+	bookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	if _, err := h.books.GetByID(uint(bookID)); err != nil {
+		return err
+	}
+
+	copies, err := h.copyRepo.GetByBookID(uint(bookID))
+	if err != nil {
+		return err
+	}
+
+	copyResponses := make([]models.BookCopyResponse, 0, len(copies))
+	for i := range copies {
+		copyResponses = append(copyResponses, newBookCopyResponse(&copies[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    copyResponses,
+	})
+}
+
+func (h *BookCopyHandler) CreateBookCopy(c *fiber.Ctx) error {
+	bookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	if _, err := h.books.GetByID(uint(bookID)); err != nil {
+		return err
+	}
+
+	var copyReq models.BookCopyRequest
+	if err := c.BodyParser(&copyReq); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Struct(&copyReq); err != nil {
+		return err
+	}
+
+	bookCopy := models.BookCopy{
+		BookID:        uint(bookID),
+		Barcode:       copyReq.Barcode,
+		ShelfLocation: copyReq.ShelfLocation,
+		Condition:     models.CopyConditionGood,
+		Status:        models.CopyStatusAvailable,
+	}
+	if copyReq.Condition != "" {
+		bookCopy.Condition = copyReq.Condition
+	}
+	if copyReq.Status != "" {
+		bookCopy.Status = copyReq.Status
+	}
+
+	if bookCopy.Barcode != "" {
+		exists, err := h.copyRepo.CheckBarcodeExists(bookCopy.Barcode, 0)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return repositories.ErrBarcodeExists
+		}
+	}
+
+	if err := h.copyRepo.Create(&bookCopy); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    newBookCopyResponse(&bookCopy),
+	})
+}
+
+func (h *BookCopyHandler) UpdateBookCopy(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("eksemplar")
+	}
+
+	existingCopy, err := h.copyRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	var copyReq models.BookCopyRequest
+	if err := c.BodyParser(&copyReq); err != nil {
+		return errInvalidBody
+	}
+
+	if err := validation.Provided(&copyReq); err != nil {
+		return err
+	}
+
+	if copyReq.Barcode != "" && copyReq.Barcode != existingCopy.Barcode {
+		exists, err := h.copyRepo.CheckBarcodeExists(copyReq.Barcode, existingCopy.ID)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return repositories.ErrBarcodeExists
+		}
+		existingCopy.Barcode = copyReq.Barcode
+	}
+
+	if copyReq.ShelfLocation != "" {
+		existingCopy.ShelfLocation = copyReq.ShelfLocation
+	}
+	if copyReq.Condition != "" {
+		existingCopy.Condition = copyReq.Condition
+	}
+
+	if err := h.copyRepo.Update(existingCopy); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newBookCopyResponse(existingCopy),
+	})
+}
+
+func (h *BookCopyHandler) UpdateBookCopyStatus(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("eksemplar")
+	}
+
+	// borrowed tidak boleh di-set manual, hanya lewat /api/loans
+	var request struct {
+		Status string `json:"status" validate:"required,oneof=available maintenance lost"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Struct(&request); err != nil {
+		return err
+	}
+
+	if err := h.copyRepo.UpdateStatus(uint(id), request.Status); err != nil {
+		return err
+	}
+
+	bookCopy, err := h.copyRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newBookCopyResponse(bookCopy),
+	})
+}
+
+func (h *BookCopyHandler) DeleteBookCopy(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("eksemplar")
+	}
+
+	if err := h.copyRepo.Delete(uint(id)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Eksemplar berhasil dihapus",
+	})
+}
+
+func newBookCopyResponse(bookCopy *models.BookCopy) models.BookCopyResponse {
+	return models.BookCopyResponse{
+		ID:            bookCopy.ID,
+		BookID:        bookCopy.BookID,
+		Barcode:       bookCopy.Barcode,
+		ShelfLocation: bookCopy.ShelfLocation,
+		Condition:     bookCopy.Condition,
+		Status:        bookCopy.Status,
+		CreatedAt:     bookCopy.CreatedAt,
+		UpdatedAt:     bookCopy.UpdatedAt,
+	}
+}
@@ -1,84 +1,162 @@
 package handlers
 
 import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/mergepatch"
 	"backend_perpustakaan_online/models"
 	"backend_perpustakaan_online/repositories"
+	"backend_perpustakaan_online/validation"
+	"encoding/json"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+var errCursorWithSearch = apperror.Validation("cursor_with_search", "Pagination cursor tidak tersedia untuk hasil pencarian, gunakan page dan limit")
+
 type BookHandler struct {
-	bookRepo *repositories.BookRepository
+	books repositories.BookStore
 }
 
-func NewBookHandler() *BookHandler {
+func NewBookHandler(books repositories.BookStore) *BookHandler {
 	return &BookHandler{
-		bookRepo: repositories.NewBookRepository(),
+		books: books,
 	}
 }
 
 func (h *BookHandler) GetAllBooks(c *fiber.Ctx) error {
-	page, _ := strconv.Atoi(c.Query("page", "1"))
-	limit, _ := strconv.Atoi(c.Query("limit", "10"))
-	search := c.Query("search")
-	status := c.Query("status")
-	category := c.Query("category")
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		return err
+	}
 
-	filter := repositories.BookFilter{
-		Search:   search,
-		Status:   status,
-		Category: category,
-		Page:     page,
-		Limit:    limit,
+	facetNames, err := parseFacets(c.Query("facets"))
+	if err != nil {
+		return err
+	}
+
+	// Pencarian memakai ranking relevansi kecuali ?sort= diisi. ?cursor= atau ?pagination=cursor
+	// memakai pagination cursor yang meta-nya berisi next_cursor dan prev_cursor.
+	cursor := c.Query("cursor")
+	useCursor := cursor != "" || c.Query("pagination") == "cursor"
+	var hits []repositories.BookHit
+	var meta any
+	var didYouMean string
+	switch {
+	case useCursor && filter.Search != "":
+		return errCursorWithSearch
+	case filter.Search != "":
+		var result *repositories.SearchResult
+		if result, err = h.books.Search(filter); err == nil {
+			hits, meta, didYouMean = result.Hits, result.Pagination, result.DidYouMean
+		}
+	case useCursor:
+		var books []models.Book
+		books, meta, err = h.books.GetAllByCursor(filter, cursor)
+		for _, book := range books {
+			hits = append(hits, repositories.BookHit{Book: book})
+		}
+	default:
+		var books []models.Book
+		books, meta, err = h.books.GetAll(filter)
+		for _, book := range books {
+			hits = append(hits, repositories.BookHit{Book: book})
+		}
+	}
+	if err != nil {
+		return err
 	}
 
-	books, pagination, err := h.bookRepo.GetAll(filter)
+	bookIDs := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		bookIDs = append(bookIDs, hit.ID)
+	}
+	copyCounts, err := h.books.CountCopies(bookIDs)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"Error": "Gagal mengambil data books",
-		})
+		return err
 	}
 
 	var bookResponses []models.BookResponse
-	for _, book := range books {
+	for _, hit := range hits {
 		bookResponses = append(bookResponses, models.BookResponse{
-			ID:          book.ID,
-			Title:       book.Title,
-			Author:      book.Author,
-			ISBN:        book.ISBN,
-			Description: book.Description,
-			Category:    book.Category,
-			TotalPages:  book.TotalPages,
-			Publisher:   book.Publisher,
-			PublisherAt: book.PublisherAt,
-			Status:      book.Status,
-			CreatedAt:   book.CreatedAt,
-			UpdateAt:    book.UpdateAt,
+			ID:          hit.ID,
+			Title:       hit.Title,
+			Author:      hit.Author,
+			ISBN:        hit.ISBN,
+			Description: hit.Description,
+			Category:    hit.Category,
+			TotalPages:  hit.TotalPages,
+			Publisher:   hit.Publisher,
+			PublisherAt: hit.PublisherAt,
+			Status:      hit.Status,
+			CreatedAt:   hit.CreatedAt,
+			UpdatedAt:   hit.UpdatedAt,
+			Version:     hit.Version,
+			CopyCounts:  copyCounts[hit.ID],
+			Score:       hit.Score,
+			Highlights:  hit.Highlights,
 		})
 	}
-	return c.JSON(fiber.Map{
+
+	response := fiber.Map{
 		"success": true,
 		"data":    bookResponses,
-		"meta":    pagination,
+		"meta":    meta,
+	}
+	// did_you_mean hanya ada jika kata yang salah ketik dikoreksi
+	if didYouMean != "" {
+		response["did_you_mean"] = didYouMean
+	}
+	if len(facetNames) > 0 {
+		facets, err := h.books.Facets(filter, facetNames)
+		if err != nil {
+			return err
+		}
+		response["facets"] = facets
+	}
+	return c.JSON(response)
+}
+
+// SuggestBooks mengembalikan judul dan penulis yang cocok dengan ?q= untuk type-ahead di kotak
+// pencarian. Kata terakhir dicocokkan sebagai awalan dan salah ketik ditoleransi.
+func (h *BookHandler) SuggestBooks(c *fiber.Ctx) error {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		return apperror.Validation("missing_query", "q wajib diisi")
+	}
+	limit, err := strconv.Atoi(c.Query("limit", "8"))
+	if err != nil || limit < 1 || limit > repositories.MaxSuggestions {
+		return apperror.Validation("invalid_limit", "limit harus antara 1 dan "+strconv.Itoa(repositories.MaxSuggestions))
+	}
+
+	suggestions, err := h.books.Suggest(q, limit)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    suggestions,
 	})
 }
 
 func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid Book ID",
-		})
+		return invalidID("buku")
 	}
 
-	book, err := h.bookRepo.GetByID(uint(id))
+	book, err := h.books.GetByID(uint(id))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Buku Tidak ada",
-		})
+		return err
+	}
+
+	copyCounts, err := h.copyCounts(book.ID)
+	if err != nil {
+		return err
 	}
 
 	bookResponse := models.BookResponse{
@@ -93,8 +171,17 @@ func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 		PublisherAt: book.PublisherAt,
 		Status:      book.Status,
 		CreatedAt:   book.CreatedAt,
-		UpdateAt:    book.UpdateAt,
+		UpdatedAt:   book.UpdatedAt,
+		Version:     book.Version,
+		CopyCounts:  copyCounts,
+	}
+
+	etag := bookETag(book)
+	c.Set(fiber.HeaderETag, etag)
+	if notModified(c, etag) {
+		return c.SendStatus(fiber.StatusNotModified)
 	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"data":    bookResponse,
@@ -104,31 +191,19 @@ func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 	var bookReq models.BookRequest
 	if err := c.BodyParser(&bookReq); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid Request Body",
-		})
+		return errInvalidBody
 	}
 
-	if bookReq.Title == "" || bookReq.Author == "" || bookReq.ISBN == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Title, Author and ISBN are required",
-		})
+	if err := validation.Struct(&bookReq); err != nil {
+		return err
 	}
 
-	exists, err := h.bookRepo.CheckISBNExists(bookReq.ISBN, 0)
+	exists, err := h.books.CheckISBNExists(bookReq.ISBN, 0)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Gagal cek ISBN",
-		})
+		return err
 	}
 	if exists {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"success": false,
-			"error":   "Buku dengan ISBN sudah ada",
-		})
+		return repositories.ErrISBNExists
 	}
 
 	book := models.Book{
@@ -142,15 +217,36 @@ func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 		PublisherAt: bookReq.PublisherAt,
 	}
 
+	if bookReq.Status == models.BookStatusBorrowed {
+		return errBorrowedViaLoans
+	}
+
+	copyCount := 1
+	if bookReq.Copies != nil {
+		copyCount = *bookReq.Copies
+	}
+
+	copyStatus := models.CopyStatusAvailable
 	if bookReq.Status != "" {
-		book.Status = bookReq.Status
+		copyStatus = bookReq.Status
+	}
+	copies := make([]models.BookCopy, copyCount)
+	for i := range copies {
+		copies[i] = models.BookCopy{
+			ShelfLocation: bookReq.ShelfLocation,
+			Condition:     models.CopyConditionGood,
+			Status:        copyStatus,
+		}
 	}
 
-	if err := h.bookRepo.Create(&book); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Gagal Membuat data buku",
-		})
+	book.Copies = copies
+	if err := h.books.Create(&book); err != nil {
+		return err
+	}
+
+	copyCounts, err := h.copyCounts(book.ID)
+	if err != nil {
+		return err
 	}
 
 	bookResponse := models.BookResponse{
@@ -165,88 +261,118 @@ func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 		PublisherAt: book.PublisherAt,
 		Status:      book.Status,
 		CreatedAt:   book.CreatedAt,
-		UpdateAt:    book.UpdateAt,
+		UpdatedAt:   book.UpdatedAt,
+		Version:     book.Version,
+		CopyCounts:  copyCounts,
 	}
 
+	c.Set(fiber.HeaderETag, bookETag(&book))
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
 		"data":    bookResponse,
 	})
 }
 
+// UpdateBook (PUT) mengganti seluruh data book. Field yang tidak dikirim dikosongkan, kecuali status
+// yang diturunkan dari eksemplar dan hanya diubah jika dikirim.
 func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid Book ID",
-		})
+		return invalidID("buku")
 	}
 
-	existingBook, err := h.bookRepo.GetByID(uint(id))
+	existingBook, err := h.books.GetByID(uint(id))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"success": false,
-			"error":   "Buku tidak ada",
-		})
+		return err
+	}
+	if err := checkIfMatch(c, existingBook); err != nil {
+		return err
 	}
 
 	var bookReq models.BookRequest
 	if err := c.BodyParser(&bookReq); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid Request Body",
-		})
+		return errInvalidBody
 	}
 
-	if bookReq.ISBN != "" && bookReq.ISBN != existingBook.ISBN {
-		exists, err := h.bookRepo.CheckISBNExists(bookReq.ISBN, uint(id))
-		if err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"success": false,
-				"error":   "Gagal cek ISBN",
-			})
-		}
-		if exists {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"success": false,
-				"error":   "Buku dengan ISBN sudah ada",
-			})
-		}
-		existingBook.ISBN = bookReq.ISBN
+	return h.replaceBook(c, existingBook, &bookReq)
+}
+
+// PatchBook (PATCH) menerapkan JSON Merge Patch (RFC 7396) ke book: field yang dikirim diganti,
+// field bernilai null dikosongkan, dan field yang tidak dikirim tetap
+func (h *BookHandler) PatchBook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
 	}
 
-	if bookReq.Title != "" {
-		existingBook.Title = bookReq.Title
+	existingBook, err := h.books.GetByID(uint(id))
+	if err != nil {
+		return err
 	}
-	if bookReq.Author != "" {
-		existingBook.Author = bookReq.Author
+	if err := checkIfMatch(c, existingBook); err != nil {
+		return err
 	}
-	if bookReq.Description != "" {
-		existingBook.Description = bookReq.Description
+
+	contentType, _, _ := strings.Cut(string(c.Request().Header.ContentType()), ";")
+	switch strings.TrimSpace(contentType) {
+	case mergepatch.ContentType, fiber.MIMEApplicationJSON:
+	default:
+		return fiber.ErrUnsupportedMediaType
 	}
-	if bookReq.Category != "" {
-		existingBook.Category = bookReq.Category
+
+	current, err := json.Marshal(bookDocument(existingBook))
+	if err != nil {
+		return err
 	}
-	if bookReq.TotalPages > 0 {
-		existingBook.TotalPages = bookReq.TotalPages
+	patched, err := mergepatch.Apply(current, c.Body())
+	if err != nil {
+		return errInvalidBody
 	}
-	if bookReq.Publisher != "" {
-		existingBook.Publisher = bookReq.Publisher
+
+	var bookReq models.BookRequest
+	if err := json.Unmarshal(patched, &bookReq); err != nil {
+		return errInvalidBody
 	}
-	if !bookReq.PublisherAt.IsZero() {
-		existingBook.PublisherAt = bookReq.PublisherAt
+
+	return h.replaceBook(c, existingBook, &bookReq)
+}
+
+// replaceBook memvalidasi bookReq sebagai data lengkap lalu menyimpannya ke existingBook
+func (h *BookHandler) replaceBook(c *fiber.Ctx, existingBook *models.Book, bookReq *models.BookRequest) error {
+	if err := validation.Struct(bookReq); err != nil {
+		return err
 	}
-	if bookReq.Status != "" {
-		existingBook.Status = bookReq.Status
+	if bookReq.Status == models.BookStatusBorrowed {
+		return errBorrowedViaLoans
 	}
 
-	if err := h.bookRepo.Update(existingBook); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "gagal update data buku",
-		})
+	exists, err := h.books.CheckISBNExists(bookReq.ISBN, existingBook.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return repositories.ErrISBNExists
+	}
+
+	existingBook.Title = bookReq.Title
+	existingBook.Author = bookReq.Author
+	existingBook.ISBN = bookReq.ISBN
+	existingBook.Description = bookReq.Description
+	existingBook.Category = bookReq.Category
+	existingBook.TotalPages = bookReq.TotalPages
+	existingBook.Publisher = bookReq.Publisher
+	existingBook.PublisherAt = bookReq.PublisherAt
+
+	// Status book diturunkan dari eksemplarnya, jadi status baru diterapkan ke eksemplar di rak
+	// bersama perubahan field lain dalam satu transaksi
+	if err := h.books.Update(existingBook, bookReq.Status); err != nil {
+		return err
 	}
+	copyCounts, err := h.copyCounts(existingBook.ID)
+	if err != nil {
+		return err
+	}
+
 	bookResponse := models.BookResponse{
 		ID:          existingBook.ID,
 		Title:       existingBook.Title,
@@ -259,36 +385,50 @@ func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 		PublisherAt: existingBook.PublisherAt,
 		Status:      existingBook.Status,
 		CreatedAt:   existingBook.CreatedAt,
-		UpdateAt:    existingBook.UpdateAt,
+		UpdatedAt:   existingBook.UpdatedAt,
+		Version:     existingBook.Version,
+		CopyCounts:  copyCounts,
 	}
 
+	c.Set(fiber.HeaderETag, bookETag(existingBook))
 	return c.JSON(fiber.Map{
 		"success": true,
 		"data":    bookResponse,
 	})
 }
 
+// bookDocument adalah book dalam bentuk request body, dokumen dasar yang di-patch PatchBook.
+// Status sengaja kosong supaya patch tanpa status tidak mengubah status eksemplar.
+func bookDocument(book *models.Book) models.BookRequest {
+	return models.BookRequest{
+		Title:       book.Title,
+		Author:      book.Author,
+		ISBN:        book.ISBN,
+		Description: book.Description,
+		Category:    book.Category,
+		TotalPages:  book.TotalPages,
+		Publisher:   book.Publisher,
+		PublisherAt: book.PublisherAt,
+	}
+}
+
 func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid Book ID",
-		})
+		return invalidID("buku")
 	}
 
-	if _, err := h.bookRepo.GetByID(uint(id)); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"success": false,
-			"error":   "Buku tidak ada",
-		})
+	book, err := h.books.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if err := checkIfMatch(c, book); err != nil {
+		return err
 	}
 
-	if err := h.bookRepo.Delete(uint(id)); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "gagal delete data buku",
-		})
+	// Version dicek ulang saat menghapus, karena book bisa berubah setelah checkIfMatch
+	if err := h.books.Delete(book.ID, book.Version); err != nil {
+		return err
 	}
 
 	return c.Status(fiber.StatusNoContent).JSON(fiber.Map{
@@ -297,13 +437,124 @@ func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 	})
 }
 
+// GetDeletedBooks menampilkan book yang ada di trash
+func (h *BookHandler) GetDeletedBooks(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	books, pagination, err := h.books.GetDeleted(page, limit)
+	if err != nil {
+		return err
+	}
+
+	bookResponses := make([]models.BookResponse, 0, len(books))
+	for _, book := range books {
+		deletedAt := book.DeletedAt.Time
+		bookResponses = append(bookResponses, models.BookResponse{
+			ID:          book.ID,
+			Title:       book.Title,
+			Author:      book.Author,
+			ISBN:        book.ISBN,
+			Description: book.Description,
+			Category:    book.Category,
+			TotalPages:  book.TotalPages,
+			Publisher:   book.Publisher,
+			PublisherAt: book.PublisherAt,
+			Status:      book.Status,
+			CreatedAt:   book.CreatedAt,
+			UpdatedAt:   book.UpdatedAt,
+			Version:     book.Version,
+			DeletedAt:   &deletedAt,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    bookResponses,
+		"meta":    pagination,
+	})
+}
+
+// RestoreBook mengeluarkan book dari trash
+func (h *BookHandler) RestoreBook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	if _, err := h.books.GetDeletedByID(uint(id)); err != nil {
+		return err
+	}
+
+	if err := h.books.Restore(uint(id)); err != nil {
+		return err
+	}
+
+	book, err := h.books.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	copyCounts, err := h.copyCounts(book.ID)
+	if err != nil {
+		return err
+	}
+
+	bookResponse := models.BookResponse{
+		ID:          book.ID,
+		Title:       book.Title,
+		Author:      book.Author,
+		ISBN:        book.ISBN,
+		Description: book.Description,
+		Category:    book.Category,
+		TotalPages:  book.TotalPages,
+		Publisher:   book.Publisher,
+		PublisherAt: book.PublisherAt,
+		Status:      book.Status,
+		CreatedAt:   book.CreatedAt,
+		UpdatedAt:   book.UpdatedAt,
+		Version:     book.Version,
+		CopyCounts:  copyCounts,
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    bookResponse,
+	})
+}
+
+// PurgeBooks menghapus permanen book yang sudah di trash lebih dari older_than_days hari
+func (h *BookHandler) PurgeBooks(c *fiber.Ctx) error {
+	days := int(config.Library.TrashRetention.Hours() / 24)
+	if raw := c.Query("older_than_days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return apperror.Validation("invalid_older_than_days", "older_than_days harus bilangan bulat tidak negatif")
+		}
+		days = parsed
+	}
+
+	deletedBefore := time.Now().AddDate(0, 0, -days)
+	purged, skipped, err := h.books.Purge(deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "Trash berhasil dibersihkan",
+		"data": fiber.Map{
+			"purged":         purged,
+			"skipped":        skipped,
+			"deleted_before": deletedBefore,
+		},
+	})
+}
+
 func (h *BookHandler) UpdateBookStatus(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid book ID",
-		})
+		return invalidID("buku")
 	}
 
 	var request struct {
@@ -311,43 +562,43 @@ func (h *BookHandler) UpdateBookStatus(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid request body",
-		})
+		return errInvalidBody
 	}
-
-	validStatuses := map[string]bool{
-		"available":   true,
-		"borrowed":    true,
-		"maintenance": true,
+	if err := validation.Struct(&request); err != nil {
+		return err
 	}
 
-	if !validStatuses[request.Status] {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"success": false,
-			"error":   "Invalid status. Must be: available, borrowed, or maintenance",
-		})
+	book, err := h.books.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+	// Status mengubah version book seperti PUT dan PATCH, jadi wajib If-Match juga
+	if err := checkIfMatch(c, book); err != nil {
+		return err
 	}
 
-	// Check if book exists
-	if _, err := h.bookRepo.GetByID(uint(id)); err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"success": false,
-			"error":   "Book not found",
-		})
+	if request.Status == models.BookStatusBorrowed {
+		return errBorrowedViaLoans
 	}
 
-	if err := h.bookRepo.UpdateStatus(uint(id), request.Status); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"success": false,
-			"error":   "Failed to update book status",
-		})
+	// Eksemplar yang sedang dipinjam tidak ikut diubah, status book dihitung ulang dari eksemplar
+	if err := h.books.UpdateStatus(book, request.Status); err != nil {
+		return err
 	}
 
+	c.Set(fiber.HeaderETag, bookETag(book))
 	return c.JSON(fiber.Map{
 		"success": true,
-		"message": "Book status updated successfully",
-		"status":  request.Status,
+		"message": "Status buku berhasil diubah",
+		"status":  book.Status,
 	})
 }
+
+// copyCounts mengambil jumlah eksemplar satu book
+func (h *BookHandler) copyCounts(bookID uint) (models.CopyCounts, error) {
+	counts, err := h.books.CountCopies([]uint{bookID})
+	if err != nil {
+		return models.CopyCounts{}, err
+	}
+	return counts[bookID], nil
+}
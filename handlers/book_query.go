@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/repositories"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseBookFilter membaca query string GET /api/books. status dan category boleh berisi beberapa
+// nilai dipisah koma.
+func parseBookFilter(c *fiber.Ctx) (repositories.BookFilter, error) {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	filter := repositories.BookFilter{
+		Search:     c.Query("search"),
+		Statuses:   splitList(c.Query("status")),
+		Categories: splitList(c.Query("category")),
+		Author:     strings.TrimSpace(c.Query("author")),
+		Publisher:  strings.TrimSpace(c.Query("publisher")),
+		Page:       page,
+		Limit:      limit,
+	}
+
+	var err error
+	if filter.MinPages, err = queryPages(c, "min_pages"); err != nil {
+		return filter, err
+	}
+	if filter.MaxPages, err = queryPages(c, "max_pages"); err != nil {
+		return filter, err
+	}
+	if filter.PublishedFrom, err = queryTime(c, "published_from", time.DateOnly); err != nil {
+		return filter, err
+	}
+	if filter.PublishedTo, err = queryTime(c, "published_to", time.DateOnly); err != nil {
+		return filter, err
+	}
+	if filter.CreatedSince, err = queryTime(c, "created_since", time.RFC3339); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedSince, err = queryTime(c, "updated_since", time.RFC3339); err != nil {
+		return filter, err
+	}
+	if filter.Sort, err = parseSort(c.Query("sort")); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// parseSort membaca ?sort= berupa kolom dipisah koma, awalan "-" untuk urutan menurun,
+// misalnya sort=title,-publisher_at
+func parseSort(raw string) ([]repositories.SortField, error) {
+	var fields []repositories.SortField
+	for _, name := range splitList(raw) {
+		field := repositories.SortField{Field: strings.TrimPrefix(name, "-"), Desc: strings.HasPrefix(name, "-")}
+		if !slices.Contains(repositories.BookSortFields, field.Field) {
+			return nil, apperror.Validation("invalid_sort", "sort harus berupa daftar dipisah koma dari: "+strings.Join(repositories.BookSortFields, ", ")+", diawali - untuk urutan menurun")
+		}
+		if slices.ContainsFunc(fields, func(f repositories.SortField) bool { return f.Field == field.Field }) {
+			return nil, apperror.Validation("invalid_sort", "kolom sort "+field.Field+" dipakai lebih dari sekali")
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parseFacets membaca ?facets= berupa daftar nama facet dipisah koma, atau "all" untuk semua facet
+func parseFacets(raw string) ([]string, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if raw == "all" {
+		return repositories.FacetNames, nil
+	}
+
+	var names []string
+	for _, name := range splitList(raw) {
+		if !slices.Contains(repositories.FacetNames, name) {
+			return nil, apperror.Validation("invalid_facet", "facets harus all atau daftar dipisah koma dari: "+strings.Join(repositories.FacetNames, ", "))
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// splitList memecah nilai dipisah koma dan membuang nilai kosong
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func queryPages(c *fiber.Ctx, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	pages, err := strconv.Atoi(raw)
+	if err != nil || pages < 1 {
+		return 0, apperror.Validation("invalid_"+name, name+" harus bilangan bulat positif")
+	}
+	return pages, nil
+}
+
+// queryTime membaca waktu dengan layout time.DateOnly atau time.RFC3339, nil jika tidak diisi
+func queryTime(c *fiber.Ctx, name, layout string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(layout, raw)
+	if err != nil {
+		format := "YYYY-MM-DD"
+		if layout == time.RFC3339 {
+			format = "timestamp RFC 3339"
+		}
+		return nil, apperror.Validation("invalid_"+name, name+" harus berformat "+format)
+	}
+	return &t, nil
+}
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+)
+
+var (
+	errInvalidBody      = apperror.Validation("invalid_body", "Body request tidak valid")
+	errBorrowedViaLoans = apperror.Validation("status_borrowed_via_loans", "Status borrowed hanya bisa diatur lewat /api/loans")
+)
+
+// invalidID adalah error untuk parameter route ID yang bukan angka, name misalnya "buku"
+func invalidID(name string) error {
+	return apperror.Validation("invalid_id", "ID "+name+" tidak valid")
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errIfMatchRequired = apperror.PreconditionRequired("if_match_required", "Header If-Match wajib berisi ETag book terakhir")
+
+// bookETag adalah entity tag book, berubah setiap kali version book naik
+func bookETag(book *models.Book) string {
+	return `"` + strconv.FormatUint(uint64(book.Version), 10) + `"`
+}
+
+// checkIfMatch mewajibkan header If-Match yang cocok dengan ETag book saat ini sebelum book diubah
+// atau dihapus, supaya perubahan dari request lain tidak tertimpa diam-diam
+func checkIfMatch(c *fiber.Ctx, book *models.Book) error {
+	ifMatch := c.Get(fiber.HeaderIfMatch)
+	if ifMatch == "" {
+		return errIfMatchRequired
+	}
+	if !etagMatch(ifMatch, bookETag(book), false) {
+		return repositories.ErrBookModified
+	}
+	return nil
+}
+
+// notModified mengecek apakah If-None-Match klien masih cocok dengan etag, sehingga GET cukup
+// dijawab 304. c.Fresh tidak dipakai karena menganggap If-Modified-Since saja sudah fresh.
+func notModified(c *fiber.Ctx, etag string) bool {
+	ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch)
+	return ifNoneMatch != "" && etagMatch(ifNoneMatch, etag, true)
+}
+
+// etagMatch mencocokkan etag dengan daftar entity tag di header. If-Match memakai perbandingan
+// strong sehingga weak tag (W/) tidak pernah cocok, If-None-Match memakai perbandingan weak.
+func etagMatch(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
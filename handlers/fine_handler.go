@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type FineHandler struct {
+	fineRepo *repositories.FineRepository
+}
+
+func NewFineHandler(fineRepo *repositories.FineRepository) *FineHandler {
+	return &FineHandler{
+		fineRepo: fineRepo,
+	}
+}
+
+// GetAllFines menampilkan semua fine untuk admin (bisa difilter user_id), atau fine milik sendiri untuk member
+func (h *FineHandler) GetAllFines(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	filter := repositories.FineFilter{
+		UserID: targetUserID(c),
+		Status: c.Query("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+
+	fines, pagination, err := h.fineRepo.GetAll(filter)
+	if err != nil {
+		return err
+	}
+
+	fineResponses := make([]models.FineResponse, 0, len(fines))
+	for i := range fines {
+		fineResponses = append(fineResponses, newFineResponse(&fines[i], nil))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    fineResponses,
+		"meta":    pagination,
+	})
+}
+
+func (h *FineHandler) GetFineByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("denda")
+	}
+
+	claims := middleware.GetClaims(c)
+	fine, err := h.fineRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if claims.Role != string(models.RoleAdmin) && fine.UserID != claims.UserID {
+		return repositories.ErrFineNotFound
+	}
+
+	transactions, err := h.fineRepo.GetTransactions(fine.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newFineResponse(fine, transactions),
+	})
+}
+
+// GetBalance menampilkan saldo denda user yang login, atau user_id mana saja untuk admin
+func (h *FineHandler) GetBalance(c *fiber.Ctx) error {
+	userID := targetUserID(c)
+	if userID == 0 {
+		userID = middleware.GetClaims(c).UserID
+	}
+
+	balance, err := h.fineRepo.Balance(userID, time.Now(), config.Library.FinePolicy)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    balance,
+		"meta": fiber.Map{
+			"block_threshold": config.Library.FineBlockThreshold,
+			"can_borrow":      balance.Total <= config.Library.FineBlockThreshold,
+		},
+	})
+}
+
+func (h *FineHandler) RecordPayment(c *fiber.Ctx) error {
+	return h.recordTransaction(c, models.FineTransactionPayment)
+}
+
+func (h *FineHandler) WaiveFine(c *fiber.Ctx) error {
+	return h.recordTransaction(c, models.FineTransactionWaiver)
+}
+
+func (h *FineHandler) recordTransaction(c *fiber.Ctx, txType string) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("denda")
+	}
+
+	var request models.FineTransactionRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return errInvalidBody
+		}
+	}
+
+	// Waiver tanpa amount menghapus seluruh sisa denda, pembayaran wajib punya amount
+	if request.Amount < 0 || (request.Amount == 0 && txType == models.FineTransactionPayment) {
+		return apperror.Validation("invalid_amount", "amount harus lebih dari 0")
+	}
+
+	recordedBy := middleware.GetClaims(c).UserID
+
+	var fine *models.Fine
+	if txType == models.FineTransactionPayment {
+		fine, err = h.fineRepo.RecordPayment(uint(id), request.Amount, request.Note, recordedBy)
+	} else {
+		fine, err = h.fineRepo.Waive(uint(id), request.Amount, request.Note, recordedBy)
+	}
+	if err != nil {
+		return err
+	}
+
+	transactions, err := h.fineRepo.GetTransactions(fine.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newFineResponse(fine, transactions),
+	})
+}
+
+// targetUserID membaca query user_id untuk admin; member selalu diarahkan ke dirinya sendiri
+func targetUserID(c *fiber.Ctx) uint {
+	claims := middleware.GetClaims(c)
+	if claims.Role != string(models.RoleAdmin) {
+		return claims.UserID
+	}
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	return uint(userID)
+}
+
+func newFineResponse(fine *models.Fine, transactions []models.FineTransaction) models.FineResponse {
+	return models.FineResponse{
+		ID:           fine.ID,
+		UserID:       fine.UserID,
+		LoanID:       fine.LoanID,
+		DaysOverdue:  fine.DaysOverdue,
+		Amount:       fine.Amount,
+		PaidAmount:   fine.PaidAmount,
+		WaivedAmount: fine.WaivedAmount,
+		Outstanding:  fine.Outstanding(),
+		Status:       fine.Status,
+		CreatedAt:    fine.CreatedAt,
+		Transactions: transactions,
+	}
+}
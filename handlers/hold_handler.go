@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HoldHandler struct {
+	holdRepo *repositories.HoldRepository
+	userRepo *repositories.UserRepository
+}
+
+func NewHoldHandler(holdRepo *repositories.HoldRepository, userRepo *repositories.UserRepository) *HoldHandler {
+	return &HoldHandler{
+		holdRepo: holdRepo,
+		userRepo: userRepo,
+	}
+}
+
+// GetBookHolds menampilkan antrean hold book. Admin melihat seluruh antrean, member hanya hold
+// miliknya sendiri beserta posisinya.
+func (h *HoldHandler) GetBookHolds(c *fiber.Ctx) error {
+	bookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	queue, err := h.holdRepo.GetQueue(uint(bookID))
+	if err != nil {
+		return err
+	}
+
+	claims := middleware.GetClaims(c)
+	holdResponses := make([]models.HoldResponse, 0, len(queue))
+	for i := range queue {
+		if claims.Role != string(models.RoleAdmin) && queue[i].UserID != claims.UserID {
+			continue
+		}
+		holdResponses = append(holdResponses, newHoldResponse(&queue[i], i+1))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    holdResponses,
+		"meta": fiber.Map{
+			"queue_length": len(queue),
+		},
+	})
+}
+
+// CreateBookHold memasukkan member ke antrean hold. Admin boleh mendaftarkan member lain lewat user_id.
+func (h *HoldHandler) CreateBookHold(c *fiber.Ctx) error {
+	bookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	var request struct {
+		UserID uint `json:"user_id"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return errInvalidBody
+		}
+	}
+
+	claims := middleware.GetClaims(c)
+	userID := claims.UserID
+	if request.UserID != 0 && request.UserID != claims.UserID {
+		if claims.Role != string(models.RoleAdmin) {
+			return apperror.Forbidden("admin_only", "Hanya admin yang bisa membuat hold untuk user lain")
+		}
+		userID = request.UserID
+	}
+
+	member, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !member.IsActive {
+		return middleware.ErrAccountInactive
+	}
+
+	hold, err := h.holdRepo.Place(member.ID, uint(bookID))
+	if err != nil {
+		return err
+	}
+
+	queue, err := h.holdRepo.GetQueue(uint(bookID))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    newHoldResponse(hold, len(queue)),
+	})
+}
+
+// CancelBookHold membatalkan hold milik sendiri, atau hold siapa saja untuk admin
+func (h *HoldHandler) CancelBookHold(c *fiber.Ctx) error {
+	bookID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("buku")
+	}
+
+	holdID, err := strconv.ParseUint(c.Params("holdId"), 10, 32)
+	if err != nil {
+		return invalidID("hold")
+	}
+
+	claims := middleware.GetClaims(c)
+	existingHold, err := h.holdRepo.GetByID(uint(holdID))
+	if err != nil {
+		return err
+	}
+	if existingHold.BookID != uint(bookID) ||
+		(claims.Role != string(models.RoleAdmin) && existingHold.UserID != claims.UserID) {
+		return repositories.ErrHoldNotFound
+	}
+
+	hold, err := h.holdRepo.Cancel(existingHold.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newHoldResponse(hold, 0),
+	})
+}
+
+func newHoldResponse(hold *models.Hold, position int) models.HoldResponse {
+	response := models.HoldResponse{
+		ID:        hold.ID,
+		BookID:    hold.BookID,
+		UserID:    hold.UserID,
+		Status:    hold.Status,
+		CopyID:    hold.CopyID,
+		ReadyAt:   hold.ReadyAt,
+		ExpiresAt: hold.ExpiresAt,
+		CreatedAt: hold.CreatedAt,
+	}
+	if hold.IsActive() {
+		response.Position = position
+	}
+	return response
+}
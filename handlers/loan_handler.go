@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errFineLimitExceeded = apperror.Forbidden("fine_limit_exceeded", "Denda belum dibayar melebihi batas, lunasi denda sebelum meminjam")
+
+type LoanHandler struct {
+	loanRepo *repositories.LoanRepository
+	userRepo *repositories.UserRepository
+	copyRepo *repositories.BookCopyRepository
+	fineRepo *repositories.FineRepository
+}
+
+func NewLoanHandler(loanRepo *repositories.LoanRepository, userRepo *repositories.UserRepository, copyRepo *repositories.BookCopyRepository, fineRepo *repositories.FineRepository) *LoanHandler {
+	return &LoanHandler{
+		loanRepo: loanRepo,
+		userRepo: userRepo,
+		copyRepo: copyRepo,
+		fineRepo: fineRepo,
+	}
+}
+
+// GetAllLoans menampilkan semua loan untuk admin, atau hanya loan milik sendiri untuk member
+func (h *LoanHandler) GetAllLoans(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	bookID, _ := strconv.ParseUint(c.Query("book_id"), 10, 32)
+
+	filter := repositories.LoanFilter{
+		UserID: uint(userID),
+		BookID: uint(bookID),
+		Active: c.QueryBool("active"),
+		Page:   page,
+		Limit:  limit,
+	}
+
+	claims := middleware.GetClaims(c)
+	if claims.Role != string(models.RoleAdmin) {
+		filter.UserID = claims.UserID
+	}
+
+	loans, pagination, err := h.loanRepo.GetAll(filter)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	loanResponses := make([]models.LoanResponse, 0, len(loans))
+	for i := range loans {
+		loanResponses = append(loanResponses, newLoanResponse(&loans[i], now))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    loanResponses,
+		"meta":    pagination,
+	})
+}
+
+func (h *LoanHandler) GetLoanByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("loan")
+	}
+
+	loan, err := h.loanRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if !canAccessLoan(c, loan) {
+		return repositories.ErrLoanNotFound
+	}
+
+	renewals, err := h.loanRepo.GetRenewals(loan.ID)
+	if err != nil {
+		return err
+	}
+
+	loanResponse := newLoanResponse(loan, time.Now())
+	loanResponse.Renewals = renewals
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    loanResponse,
+	})
+}
+
+// CreateLoan melakukan checkout eksemplar buku. Admin boleh meminjamkan atas nama user lain lewat user_id.
+func (h *LoanHandler) CreateLoan(c *fiber.Ctx) error {
+	var loanReq models.LoanRequest
+	if err := c.BodyParser(&loanReq); err != nil {
+		return errInvalidBody
+	}
+
+	if loanReq.Barcode != "" {
+		bookCopy, err := h.copyRepo.GetByBarcode(loanReq.Barcode)
+		if err != nil {
+			return err
+		}
+		loanReq.CopyID = bookCopy.ID
+	}
+
+	if loanReq.BookID == 0 && loanReq.CopyID == 0 {
+		return apperror.Validation("missing_fields", "book_id, copy_id, atau barcode wajib diisi")
+	}
+
+	claims := middleware.GetClaims(c)
+	userID := claims.UserID
+	if loanReq.UserID != 0 && loanReq.UserID != claims.UserID {
+		if claims.Role != string(models.RoleAdmin) {
+			return apperror.Forbidden("admin_only", "Hanya admin yang bisa membuat loan untuk user lain")
+		}
+		userID = loanReq.UserID
+	}
+
+	borrower, err := h.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+	if !borrower.IsActive {
+		return middleware.ErrAccountInactive
+	}
+
+	balance, err := h.fineRepo.Balance(borrower.ID, time.Now(), config.Library.FinePolicy)
+	if err != nil {
+		return err
+	}
+	if balance.Total > config.Library.FineBlockThreshold {
+		return errFineLimitExceeded.WithDetails(fiber.Map{"balance": balance})
+	}
+
+	loan, err := h.loanRepo.Checkout(borrower.ID, loanReq.BookID, loanReq.CopyID, time.Now(), config.Library.LoanPeriod)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    newLoanResponse(loan, time.Now()),
+	})
+}
+
+func (h *LoanHandler) ReturnLoan(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("loan")
+	}
+
+	loan, fine, err := h.loanRepo.Return(uint(id), time.Now(), config.Library.FinePolicy)
+	if err != nil {
+		return err
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"data":    newLoanResponse(loan, time.Now()),
+	}
+	if fine != nil {
+		response["fine"] = newFineResponse(fine, nil)
+	}
+	return c.JSON(response)
+}
+
+// RenewLoan memperpanjang loan milik sendiri, atau loan siapa saja untuk admin
+func (h *LoanHandler) RenewLoan(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("loan")
+	}
+
+	existingLoan, err := h.loanRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+	if !canAccessLoan(c, existingLoan) {
+		return repositories.ErrLoanNotFound
+	}
+
+	renewedBy := middleware.GetClaims(c).UserID
+	loan, err := h.loanRepo.Renew(existingLoan.ID, time.Now(), config.Library.LoanPeriod, config.Library.MaxRenewals, renewedBy)
+	if err != nil {
+		return err
+	}
+
+	renewals, err := h.loanRepo.GetRenewals(loan.ID)
+	if err != nil {
+		return err
+	}
+
+	loanResponse := newLoanResponse(loan, time.Now())
+	loanResponse.Renewals = renewals
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    loanResponse,
+	})
+}
+
+// canAccessLoan membatasi member hanya ke loan miliknya sendiri, admin bisa semua
+func canAccessLoan(c *fiber.Ctx, loan *models.Loan) bool {
+	claims := middleware.GetClaims(c)
+	return claims.Role == string(models.RoleAdmin) || claims.UserID == loan.UserID
+}
+
+func newLoanResponse(loan *models.Loan, now time.Time) models.LoanResponse {
+	response := models.LoanResponse{
+		ID:           loan.ID,
+		UserID:       loan.UserID,
+		BookID:       loan.BookID,
+		BookTitle:    loan.Book.Title,
+		CopyID:       loan.CopyID,
+		BorrowedAt:   loan.BorrowedAt,
+		DueAt:        loan.DueAt,
+		ReturnedAt:   loan.ReturnedAt,
+		IsOverdue:    loan.IsOverdue(now),
+		RenewalCount: loan.RenewalCount,
+	}
+	if loan.Copy != nil {
+		response.Barcode = loan.Copy.Barcode
+	}
+	return response
+}
@@ -1 +1,221 @@
 package handlers
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"backend_perpustakaan_online/validation"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UserHandler struct {
+	userRepo *repositories.UserRepository
+}
+
+func NewUserHandler(userRepo *repositories.UserRepository) *UserHandler {
+	return &UserHandler{
+		userRepo: userRepo,
+	}
+}
+
+func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	limit, _ := strconv.Atoi(c.Query("limit", "10"))
+
+	filter := repositories.UserFilter{
+		Search: c.Query("search"),
+		Role:   c.Query("role"),
+		Page:   page,
+		Limit:  limit,
+	}
+
+	if isActive := c.Query("is_active"); isActive != "" {
+		active, err := strconv.ParseBool(isActive)
+		if err != nil {
+			return apperror.Validation("invalid_is_active", "is_active harus true atau false")
+		}
+		filter.IsActive = &active
+	}
+
+	users, pagination, err := h.userRepo.GetAll(filter)
+	if err != nil {
+		return err
+	}
+
+	userResponses := make([]models.UserResponse, 0, len(users))
+	for i := range users {
+		userResponses = append(userResponses, newUserResponse(&users[i]))
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    userResponses,
+		"meta":    pagination,
+	})
+}
+
+func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("user")
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newUserResponse(user),
+	})
+}
+
+func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
+	var userReq models.UserRequest
+	if err := c.BodyParser(&userReq); err != nil {
+		return errInvalidBody
+	}
+
+	userReq.Email = strings.ToLower(strings.TrimSpace(userReq.Email))
+	if err := validation.Struct(&userReq); err != nil {
+		return err
+	}
+
+	if userReq.Role == "" {
+		userReq.Role = models.RoleUser
+	}
+
+	exists, err := h.userRepo.CheckEmailExists(userReq.Email, 0)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return repositories.ErrEmailExists
+	}
+
+	user := models.User{
+		Name:     userReq.Name,
+		Email:    userReq.Email,
+		Password: userReq.Password,
+		Role:     userReq.Role,
+		IsActive: true,
+	}
+
+	if err := h.userRepo.Create(&user); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"data":    newUserResponse(&user),
+	})
+}
+
+func (h *UserHandler) UpdateUserRole(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("user")
+	}
+
+	var request struct {
+		Role models.UserRole `json:"role" validate:"required,oneof=admin user"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Struct(&request); err != nil {
+		return err
+	}
+
+	if isSelf(c, uint(id)) && request.Role != models.RoleAdmin {
+		return apperror.Validation("self_demotion", "Admin tidak bisa menurunkan role sendiri")
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	if err := h.userRepo.UpdateRole(user.ID, request.Role); err != nil {
+		return err
+	}
+	user.Role = request.Role
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newUserResponse(user),
+	})
+}
+
+func (h *UserHandler) UpdateUserActive(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("user")
+	}
+
+	var request struct {
+		IsActive *bool `json:"is_active" validate:"required"`
+	}
+
+	if err := c.BodyParser(&request); err != nil {
+		return errInvalidBody
+	}
+	if err := validation.Struct(&request); err != nil {
+		return err
+	}
+
+	if isSelf(c, uint(id)) && !*request.IsActive {
+		return apperror.Validation("self_deactivation", "Admin tidak bisa menonaktifkan akun sendiri")
+	}
+
+	user, err := h.userRepo.GetByID(uint(id))
+	if err != nil {
+		return err
+	}
+
+	if err := h.userRepo.SetActive(user.ID, *request.IsActive); err != nil {
+		return err
+	}
+	user.IsActive = *request.IsActive
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    newUserResponse(user),
+	})
+}
+
+func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return invalidID("user")
+	}
+
+	if isSelf(c, uint(id)) {
+		return apperror.Validation("self_deletion", "Admin tidak bisa menghapus akun sendiri")
+	}
+
+	if _, err := h.userRepo.GetByID(uint(id)); err != nil {
+		return err
+	}
+
+	if err := h.userRepo.Delete(uint(id)); err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "User berhasil dihapus",
+	})
+}
+
+// isSelf mengecek apakah target request adalah user yang sedang login
+func isSelf(c *fiber.Ctx, id uint) bool {
+	claims := middleware.GetClaims(c)
+	return claims != nil && claims.UserID == id
+}
@@ -0,0 +1,98 @@
+// Package isbn memvalidasi ISBN-10 dan ISBN-13 beserta check digit-nya dan mengubahnya ke
+// bentuk kanonik yang disimpan di database
+package isbn
+
+import "strings"
+
+// Valid mengecek apakah s adalah ISBN-10 atau ISBN-13 dengan check digit yang benar.
+// Tanda hubung dan spasi diabaikan, check digit ISBN-10 boleh X.
+func Valid(s string) bool {
+	_, ok := Normalize(s)
+	return ok
+}
+
+// Normalize mengubah ISBN-10 atau ISBN-13 yang valid ke bentuk kanonik, yaitu ISBN-13 tanpa
+// tanda hubung. ISBN-10 dikonversi dengan prefix 978. ok false jika s bukan ISBN yang valid.
+func Normalize(s string) (string, bool) {
+	digits := Compact(s)
+	switch {
+	case len(digits) == 10 && valid10(digits):
+		return to13(digits), true
+	case len(digits) == 13 && valid13(digits):
+		return digits, true
+	}
+	return "", false
+}
+
+// Compact membuang tanda hubung dan spasi lalu menyeragamkan x menjadi X, tanpa memeriksa
+// check digit. Dipakai untuk mencocokkan potongan ISBN di pencarian.
+func Compact(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ':
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// valid10: jumlah digit dikali bobot 10 sampai 1 harus habis dibagi 11, X di posisi terakhir bernilai 10
+func valid10(digits string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		c := digits[i]
+		var value int
+		switch {
+		case c >= '0' && c <= '9':
+			value = int(c - '0')
+		case c == 'X' && i == 9:
+			value = 10
+		default:
+			return false
+		}
+		sum += value * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// valid13: check digit di posisi terakhir harus sama dengan hasil checkDigit13 dari 12 digit pertama
+func valid13(digits string) bool {
+	if !isDigits(digits) {
+		return false
+	}
+	return digits[12] == checkDigit13(digits[:12])
+}
+
+// to13 mengubah ISBN-10 yang sudah valid ke ISBN-13: prefix 978, sembilan digit pertama,
+// lalu check digit baru karena rumus ISBN-13 berbeda
+func to13(isbn10 string) string {
+	digits := "978" + isbn10[:9]
+	return digits + string(checkDigit13(digits))
+}
+
+// checkDigit13 menghitung check digit ISBN-13 dari 12 digit: bobot 1 dan 3 bergantian,
+// check digit melengkapi jumlahnya menjadi kelipatan 10
+func checkDigit13(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(digits[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package isbn
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		input string
+		want  string
+		ok    bool
+	}{
+		{"isbn-13", "9780306406157", "9780306406157", true},
+		{"isbn-13 with hyphens", "978-0-306-40615-7", "9780306406157", true},
+		{"isbn-13 with spaces", "978 0 306 40615 7", "9780306406157", true},
+		{"isbn-10 converted", "0-306-40615-2", "9780306406157", true},
+		{"isbn-10 check digit X", "0-8044-2957-X", "9780804429573", true},
+		{"isbn-10 lowercase x", "080442957x", "9780804429573", true},
+		{"isbn-13 wrong check digit", "978-0-306-40615-8", "", false},
+		{"isbn-10 wrong check digit", "0-306-40615-3", "", false},
+		{"X not last in isbn-10", "X-306-40615-2", "", false},
+		{"X in isbn-13", "978-0-8044-2957-X", "", false},
+		{"letters", "978-0-306-4O615-7", "", false},
+		{"too short", "030640615", "", false},
+		{"too long", "97803064061570", "", false},
+		{"empty", "", "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Normalize(tt.input)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+			}
+			if Valid(tt.input) != tt.ok {
+				t.Errorf("Valid(%q) = %v, want %v", tt.input, !tt.ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	for _, tt := range []struct {
+		input string
+		want  string
+	}{
+		{"978-0-306-40615-7", "9780306406157"},
+		{"0 8044 2957 x", "080442957X"},
+		{"1984", "1984"},
+		{"", ""},
+	} {
+		if got := Compact(tt.input); got != tt.want {
+			t.Errorf("Compact(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
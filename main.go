@@ -1,18 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
-	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/joho/godotenv"
 
 	"backend_perpustakaan_online/config"
 	"backend_perpustakaan_online/database"
-	"backend_perpustakaan_online/handlers"
+	"backend_perpustakaan_online/repositories"
+	"backend_perpustakaan_online/server"
 )
 
 func main() {
@@ -21,84 +23,64 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	config.ConnectDB()
-
-	database.Migrate()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
 
-	if os.Getenv("SEED_DATA") == "true" {
-		database.Seeder()
+	if err := config.InitJWT(); err != nil {
+		log.Fatal("Konfigurasi JWT tidak valid: ", err)
 	}
 
-	// Initialize Fiber app
-	app := fiber.New(fiber.Config{
-		AppName:      "Library API",
-		ErrorHandler: errorHandler,
-	})
-
-	// Middleware
-	app.Use(logger.New())
-	app.Use(recover.New())
-	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,PATCH,DELETE",
-		AllowHeaders: "Content-Type,Authorization",
-	}))
-
-	bookHandler := handlers.NewBookHandler()
-
-	api := app.Group("/api")
-
-	books := api.Group("/books")
-	books.Get("/", bookHandler.GetAllBooks)
-	books.Get("/:id", bookHandler.GetBookByID)
-	books.Post("/", bookHandler.CreateBook)
-	books.Put("/:id", bookHandler.UpdateBook)
-	books.Patch("/:id", bookHandler.UpdateBook)
-	books.Delete("/:id", bookHandler.DeleteBook)
-	books.Patch("/:id/status", bookHandler.UpdateBookStatus)
-
-	app.Get("/health", func(c *fiber.Ctx) error {
-		sqlDB, err := config.DB.DB()
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Database connection error",
-			})
-		}
+	config.InitLibrary()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-		if err := sqlDB.Ping(); err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Database ping failed",
-			})
+	// Demo mode menyajikan katalog buku dari memori tanpa database; route yang butuh database
+	// (auth, users, copies, holds, loans, fines) tidak didaftarkan
+	cfg := server.Config{}
+	var workers []server.Worker
+	if os.Getenv("DEMO_MODE") == "true" {
+		log.Println("DEMO_MODE aktif: katalog buku disimpan di memori, data hilang saat server berhenti")
+		cfg.Books = repositories.NewMemoryBookStore(database.SampleBooks()...)
+	} else {
+		config.ConnectDB()
+
+		database.Migrate()
+
+		if os.Getenv("SEED_DATA") == "true" {
+			database.Seeder()
 		}
 
-		return c.JSON(fiber.Map{
-			"status":   "ok",
-			"message":  "Library API is running",
-			"database": "connected",
-		})
-	})
+		// Book lama dan book dari Seeder belum punya indeks pencarian
+		indexed, err := repositories.NewBookRepository(config.DB).IndexMissing()
+		if err != nil {
+			log.Fatal("Gagal mengindeks buku untuk pencarian: ", err)
+		}
+		if indexed > 0 {
+			log.Printf("%d buku diindeks untuk pencarian", indexed)
+		}
 
-	app.Use(func(c *fiber.Ctx) error {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"success": false,
-			"error":   "Endpoint not found",
+		cfg.DB = config.DB
+		holdRepo := repositories.NewHoldRepository(config.DB)
+		workers = append(workers, func(ctx context.Context) {
+			server.ExpireHolds(ctx, holdRepo, time.Minute)
 		})
-	})
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "3000"
 	}
 
-	log.Printf("Server running on port %s", port)
-	log.Fatal(app.Listen(":" + port))
-}
+	shutdownTimeout := 10 * time.Second
+	if seconds, err := strconv.Atoi(os.Getenv("SHUTDOWN_TIMEOUT_SECONDS")); err == nil && seconds > 0 {
+		shutdownTimeout = time.Duration(seconds) * time.Second
+	}
 
-func errorHandler(c *fiber.Ctx, err error) error {
-	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-		"success": false,
-		"error":   "Internal server error",
-	})
+	log.Printf("Server running on port %s", port)
+	if err := server.Run(ctx, server.New(cfg), ":"+port, cfg.DB, shutdownTimeout, workers...); err != nil {
+		log.Fatal(err)
+	}
 }
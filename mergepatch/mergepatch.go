@@ -0,0 +1,42 @@
+// Package mergepatch menerapkan JSON Merge Patch (RFC 7396) ke dokumen JSON
+package mergepatch
+
+import "encoding/json"
+
+// ContentType adalah media type untuk request body berisi merge patch
+const ContentType = "application/merge-patch+json"
+
+// Apply menerapkan patch ke dokumen target dan mengembalikan dokumen hasilnya. Member patch yang
+// bernilai null menghapus member target, member yang tidak disebut di patch tetap.
+func Apply(target, patch []byte) ([]byte, error) {
+	var targetDoc, patchDoc any
+	if err := json.Unmarshal(target, &targetDoc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(targetDoc, patchDoc))
+}
+
+// merge mengikuti fungsi MergePatch di RFC 7396 bagian 2: patch yang bukan object menggantikan
+// target seluruhnya, object digabung rekursif per member
+func merge(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = merge(targetObject[name], value)
+	}
+	return targetObject
+}
@@ -0,0 +1,63 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestApply memakai contoh dari RFC 7396 lampiran A
+func TestApply(t *testing.T) {
+	for _, tt := range []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		got, err := Apply([]byte(tt.target), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("Apply(%s, %s) error: %v", tt.target, tt.patch, err)
+			continue
+		}
+		if !equalJSON(t, got, []byte(tt.want)) {
+			t.Errorf("Apply(%s, %s) = %s, want %s", tt.target, tt.patch, got, tt.want)
+		}
+	}
+}
+
+func TestApplyInvalidJSON(t *testing.T) {
+	if _, err := Apply([]byte(`{"a":`), []byte(`{}`)); err == nil {
+		t.Error("invalid target accepted")
+	}
+	if _, err := Apply([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Error("invalid patch accepted")
+	}
+}
+
+func equalJSON(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var docA, docB any
+	if err := json.Unmarshal(a, &docA); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &docB); err != nil {
+		t.Fatal(err)
+	}
+	encodedA, _ := json.Marshal(docA)
+	encodedB, _ := json.Marshal(docB)
+	return string(encodedA) == string(encodedB)
+}
@@ -1 +1,83 @@
 package middleware
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var errInvalidToken = apperror.Unauthorized("invalid_token", "Token tidak valid atau sudah kedaluwarsa")
+
+// ClaimsKey adalah key c.Locals tempat Claims dari token disimpan
+const ClaimsKey = "claims"
+
+// ErrAccountInactive untuk user yang dinonaktifkan admin
+var ErrAccountInactive = apperror.Forbidden("account_inactive", "Akun sudah dinonaktifkan")
+
+// Protected memvalidasi header Authorization: Bearer <token> dan menyimpan Claims ke c.Locals.
+// Jika users tidak nil, user diambil ulang di setiap request supaya deaktivasi, penghapusan, dan
+// perubahan role langsung berlaku tanpa menunggu token kedaluwarsa; role di Claims diganti role
+// terbaru. Tanpa users (demo mode) Claims dari token dipakai apa adanya.
+func Protected(users *repositories.UserRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		header := c.Get(fiber.HeaderAuthorization)
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || strings.TrimSpace(tokenString) == "" {
+			return apperror.Unauthorized("missing_token", "Token tidak ada atau formatnya salah")
+		}
+
+		claims, err := config.JWT.ValidateAnyToken(strings.TrimSpace(tokenString))
+		if err != nil || claims.TokenType != config.TokenTypeAccess {
+			return errInvalidToken
+		}
+
+		if users != nil {
+			user, err := users.GetByID(claims.UserID)
+			if errors.Is(err, repositories.ErrUserNotFound) {
+				return errInvalidToken
+			}
+			if err != nil {
+				return err
+			}
+			if !user.IsActive {
+				return ErrAccountInactive
+			}
+			claims.Role = string(user.Role)
+		}
+
+		c.Locals(ClaimsKey, claims)
+		return c.Next()
+	}
+}
+
+// RequireRole hanya meloloskan request yang role-nya ada di daftar roles, harus dipasang setelah Protected
+func RequireRole(roles ...models.UserRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims := GetClaims(c)
+		if claims == nil {
+			return apperror.Unauthorized("unauthorized", "Harus login terlebih dahulu")
+		}
+
+		for _, role := range roles {
+			if claims.Role == string(role) {
+				return c.Next()
+			}
+		}
+
+		return apperror.Forbidden("insufficient_role", "Role tidak punya akses ke endpoint ini")
+	}
+}
+
+// GetClaims mengambil Claims yang disimpan oleh Protected, nil jika request tidak terautentikasi
+func GetClaims(c *fiber.Ctx) *config.Claims {
+	claims, ok := c.Locals(ClaimsKey).(*config.Claims)
+	if !ok {
+		return nil
+	}
+	return claims
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/database"
+)
+
+const migrateUsage = `Usage: perpustakaan migrate <command>
+
+Commands:
+  up            jalankan semua migration yang pending
+  down [n|all]  rollback n migration terakhir (default 1)
+  status        tampilkan migration yang sudah dan belum dijalankan`
+
+// runMigrateCommand menangani subcommand `migrate up|down|status` tanpa menyalakan server
+func runMigrateCommand(args []string) {
+	if len(args) == 0 || (args[0] != "up" && args[0] != "down" && args[0] != "status") {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	config.ConnectDB()
+
+	migrator, err := database.NewMigrator(config.DB)
+	if err != nil {
+		log.Fatal("Gagal menyiapkan migration: ", err)
+	}
+
+	switch args[0] {
+	case "up":
+		migrations, err := migrator.Up()
+		for _, migration := range migrations {
+			fmt.Printf("up   %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			fmt.Println("Tidak ada migration pending")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if args[1] == "all" {
+				steps = len(migrator.Migrations)
+			} else if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal("Jumlah rollback harus angka positif atau all")
+			}
+		}
+
+		migrations, err := migrator.Down(steps)
+		for _, migration := range migrations {
+			fmt.Printf("down %d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(migrations) == 0 {
+			fmt.Println("Tidak ada migration untuk di-rollback")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		w.Flush()
+	}
+}
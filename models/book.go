@@ -1,45 +1,68 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BookStatusAvailable   = "available"
+	BookStatusBorrowed    = "borrowed"
+	BookStatusMaintenance = "maintenance"
+)
 
 type Book struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Title       string    `json:"title" gorm:"type:varchar(255);not null"`
-	Author      string    `json:"author" gorm:"type:varchar(255);not null"`
-	ISBN        string    `json:"isbn" gorm:"type:varchar(20);uniqueIndex;not null"`
-	Description string    `json:"description" gorm:"type:text"`
-	Category    string    `json:"category" gorm:"type:varchar(100);"`
-	TotalPages  int       `json:"total_pages" gorm:"type:int;"`
-	Publisher   string    `json:"publisher" gorm:"type:varchar(255);"`
-	PublisherAt time.Time `json:"publisher_at" gorm:"type:date"`
-	Status      string    `json:"status" gorm:"type:enum('available','borrowed','maintenance');default:'available';'"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdateAt    time.Time `json:"updated_at"`
-	DeletedAt   time.Time `json:"deleted_at"`
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title       string         `json:"title" gorm:"type:varchar(255);not null"`
+	Author      string         `json:"author" gorm:"type:varchar(255);not null"`
+	ISBN        string         `json:"isbn" gorm:"type:varchar(20);uniqueIndex;not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	Category    string         `json:"category" gorm:"type:varchar(100);"`
+	TotalPages  int            `json:"total_pages" gorm:"type:int;"`
+	Publisher   string         `json:"publisher" gorm:"type:varchar(255);"`
+	PublisherAt time.Time      `json:"publisher_at" gorm:"type:date"`
+	Status      string         `json:"status" gorm:"type:varchar(20);default:'available';check:chk_books_status,status IN ('available','borrowed','maintenance')"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+	// Version naik setiap kali book berubah, dipakai sebagai ETag
+	Version uint `json:"version" gorm:"not null;default:1"`
+	// Copies hanya dipakai saat Create untuk eksemplar awal, tidak di-preload
+	Copies []BookCopy `json:"-" gorm:"foreignKey:BookID"`
 }
 
 type BookRequest struct {
-	Title       string    `json:"title" validate:"required"`
-	Author      string    `json:"author" validate:"required"`
-	ISBN        string    `json:"isbn" validate:"required"`
+	Title       string    `json:"title" validate:"required,max=255"`
+	Author      string    `json:"author" validate:"required,max=255"`
+	ISBN        string    `json:"isbn" validate:"required,isbn"`
 	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	TotalPages  int       `json:"total_pages"`
-	Publisher   string    `json:"publisher"`
-	PublisherAt time.Time `json:"publisher_at"`
-	Status      string    `json:"status"`
+	Category    string    `json:"category" validate:"max=100"`
+	TotalPages  int       `json:"total_pages" validate:"omitempty,min=1,max=10000"`
+	Publisher   string    `json:"publisher" validate:"max=255"`
+	PublisherAt time.Time `json:"publisher_at" validate:"notfuture"`
+	Status      string    `json:"status" validate:"omitempty,oneof=available borrowed maintenance"`
+	// Copies adalah jumlah eksemplar awal saat buku dibuat, default 1
+	Copies        *int   `json:"copies,omitempty" validate:"omitempty,min=0,max=100"`
+	ShelfLocation string `json:"shelf_location,omitempty" validate:"max=100"`
 }
 type BookResponse struct {
-	ID          uint      `json:"id"`
-	Title       string    `json:"title"`
-	Author      string    `json:"author"`
-	ISBN        string    `json:"isbn"`
-	Description string    `json:"description"`
-	Category    string    `json:"category"`
-	TotalPages  int       `json:"total_pages"`
-	Publisher   string    `json:"publisher"`
-	PublisherAt time.Time `json:"publisher_at"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdateAt    time.Time `json:"updated_at"`
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Author      string     `json:"author"`
+	ISBN        string     `json:"isbn"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	TotalPages  int        `json:"total_pages"`
+	Publisher   string     `json:"publisher"`
+	PublisherAt time.Time  `json:"publisher_at"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Version     uint       `json:"version"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	CopyCounts
+	// Score dan Highlights hanya diisi pada hasil pencarian ?search=
+	Score      float64           `json:"score,omitempty"`
+	Highlights map[string]string `json:"highlights,omitempty"`
 }
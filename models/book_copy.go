@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+const (
+	CopyStatusAvailable   = "available"
+	CopyStatusBorrowed    = "borrowed"
+	CopyStatusMaintenance = "maintenance"
+	CopyStatusLost        = "lost"
+	// CopyStatusOnHold berarti eksemplar disisihkan untuk hold yang siap diambil
+	CopyStatusOnHold = "on_hold"
+)
+
+const (
+	CopyConditionNew     = "new"
+	CopyConditionGood    = "good"
+	CopyConditionFair    = "fair"
+	CopyConditionPoor    = "poor"
+	CopyConditionDamaged = "damaged"
+)
+
+// BookCopy adalah satu eksemplar fisik dari sebuah Book yang bisa dipinjam
+type BookCopy struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	BookID        uint      `json:"book_id" gorm:"not null;index"`
+	Book          Book      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Barcode       string    `json:"barcode" gorm:"type:varchar(50);uniqueIndex;not null"`
+	ShelfLocation string    `json:"shelf_location" gorm:"type:varchar(100)"`
+	Condition     string    `json:"condition" gorm:"type:varchar(20);default:'good'"`
+	Status        string    `json:"status" gorm:"type:varchar(20);default:'available';index;check:chk_book_copies_status,status IN ('available','borrowed','maintenance','lost','on_hold')"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type BookCopyRequest struct {
+	Barcode       string `json:"barcode" validate:"max=50"`
+	ShelfLocation string `json:"shelf_location" validate:"max=100"`
+	Condition     string `json:"condition" validate:"omitempty,oneof=new good fair poor damaged"`
+	// Status borrowed dan on_hold hanya diatur lewat loan dan hold
+	Status string `json:"status" validate:"omitempty,oneof=available maintenance lost"`
+}
+
+type BookCopyResponse struct {
+	ID            uint      `json:"id"`
+	BookID        uint      `json:"book_id"`
+	Barcode       string    `json:"barcode"`
+	ShelfLocation string    `json:"shelf_location"`
+	Condition     string    `json:"condition"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// CopyCounts adalah ringkasan ketersediaan eksemplar sebuah Book
+type CopyCounts struct {
+	Total     int `json:"total_copies"`
+	Available int `json:"available_copies"`
+}
+
+// DeriveBookStatus menghitung status Book dari status eksemplarnya: available jika ada satu
+// eksemplar tersedia, borrowed jika ada yang dipinjam atau disisihkan untuk hold, selain itu maintenance
+func DeriveBookStatus(copyStatuses []string) string {
+	status := BookStatusMaintenance
+	for _, s := range copyStatuses {
+		switch s {
+		case CopyStatusAvailable:
+			return BookStatusAvailable
+		case CopyStatusBorrowed, CopyStatusOnHold:
+			status = BookStatusBorrowed
+		}
+	}
+	return status
+}
@@ -0,0 +1,10 @@
+package models
+
+// BookSearchTerm adalah satu baris inverted index pencarian katalog: berapa kali Term muncul di
+// Field milik book. Diisi ulang oleh BookRepository setiap kali book dibuat atau diubah.
+type BookSearchTerm struct {
+	BookID    uint   `gorm:"primaryKey"`
+	Field     string `gorm:"primaryKey;type:varchar(20)"`
+	Term      string `gorm:"primaryKey;type:varchar(64);index"`
+	Frequency int    `gorm:"not null"`
+}
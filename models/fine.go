@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+const (
+	FineStatusOutstanding = "outstanding"
+	FineStatusPaid        = "paid"
+	FineStatusWaived      = "waived"
+)
+
+const (
+	FineTransactionPayment = "payment"
+	FineTransactionWaiver  = "waiver"
+)
+
+// Fine adalah denda keterlambatan untuk satu Loan. Semua nominal dalam satuan rupiah.
+type Fine struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       uint      `json:"user_id" gorm:"not null;index"`
+	User         User      `json:"-" gorm:"constraint:OnDelete:RESTRICT"`
+	LoanID       uint      `json:"loan_id" gorm:"not null;uniqueIndex"`
+	Loan         Loan      `json:"-" gorm:"constraint:OnDelete:RESTRICT"`
+	DaysOverdue  int       `json:"days_overdue" gorm:"not null"`
+	Amount       int64     `json:"amount" gorm:"not null"`
+	PaidAmount   int64     `json:"paid_amount" gorm:"not null;default:0"`
+	WaivedAmount int64     `json:"waived_amount" gorm:"not null;default:0"`
+	Status       string    `json:"status" gorm:"type:varchar(20);default:'outstanding';index;check:chk_fines_status,status IN ('outstanding','paid','waived')"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// FineTransaction adalah satu baris ledger pembayaran atau penghapusan denda
+type FineTransaction struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	FineID       uint      `json:"fine_id" gorm:"not null;index"`
+	Fine         Fine      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Type         string    `json:"type" gorm:"type:varchar(20);not null;check:chk_fine_transactions_type,type IN ('payment','waiver')"`
+	Amount       int64     `json:"amount" gorm:"not null"`
+	Note         string    `json:"note" gorm:"type:varchar(255)"`
+	RecordedByID uint      `json:"recorded_by_id" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+type FineTransactionRequest struct {
+	Amount int64  `json:"amount"`
+	Note   string `json:"note"`
+}
+
+type FineResponse struct {
+	ID           uint              `json:"id"`
+	UserID       uint              `json:"user_id"`
+	LoanID       uint              `json:"loan_id"`
+	DaysOverdue  int               `json:"days_overdue"`
+	Amount       int64             `json:"amount"`
+	PaidAmount   int64             `json:"paid_amount"`
+	WaivedAmount int64             `json:"waived_amount"`
+	Outstanding  int64             `json:"outstanding"`
+	Status       string            `json:"status"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Transactions []FineTransaction `json:"transactions,omitempty"`
+}
+
+// FineBalance merangkum denda member: Outstanding dari denda yang sudah tercatat, Accruing dari
+// pinjaman aktif yang sudah lewat jatuh tempo
+type FineBalance struct {
+	UserID      uint  `json:"user_id"`
+	Outstanding int64 `json:"outstanding"`
+	Accruing    int64 `json:"accruing"`
+	Total       int64 `json:"total"`
+}
+
+// FinePolicy adalah tarif denda harian dan batas maksimum denda per pinjaman
+type FinePolicy struct {
+	DailyRate int64
+	MaxAmount int64
+}
+
+// Calculate menghitung denda untuk sejumlah hari terlambat, dibatasi MaxAmount jika di-set
+func (p FinePolicy) Calculate(daysOverdue int) int64 {
+	if daysOverdue <= 0 {
+		return 0
+	}
+	amount := int64(daysOverdue) * p.DailyRate
+	if p.MaxAmount > 0 && amount > p.MaxAmount {
+		amount = p.MaxAmount
+	}
+	return amount
+}
+
+// Outstanding adalah sisa denda yang belum dibayar atau dihapus
+func (f *Fine) Outstanding() int64 {
+	return f.Amount - f.PaidAmount - f.WaivedAmount
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+const (
+	HoldStatusWaiting   = "waiting"
+	HoldStatusReady     = "ready"
+	HoldStatusFulfilled = "fulfilled"
+	HoldStatusCancelled = "cancelled"
+	HoldStatusExpired   = "expired"
+)
+
+// Hold adalah antrean reservasi member untuk sebuah Book. Hold yang "ready" memegang satu
+// eksemplar (CopyID) sampai diambil lewat checkout atau kedaluwarsa pada ExpiresAt.
+type Hold struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	BookID    uint       `json:"book_id" gorm:"not null;index:idx_holds_book_status"`
+	Book      Book       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      User       `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	Status    string     `json:"status" gorm:"type:varchar(20);default:'waiting';index:idx_holds_book_status;check:chk_holds_status,status IN ('waiting','ready','fulfilled','cancelled','expired')"`
+	CopyID    *uint      `json:"copy_id"`
+	Copy      *BookCopy  `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	ReadyAt   *time.Time `json:"ready_at"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+type HoldResponse struct {
+	ID        uint       `json:"id"`
+	BookID    uint       `json:"book_id"`
+	UserID    uint       `json:"user_id"`
+	Status    string     `json:"status"`
+	Position  int        `json:"position,omitempty"`
+	CopyID    *uint      `json:"copy_id,omitempty"`
+	ReadyAt   *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsActive mengecek apakah hold masih di antrean (waiting) atau menunggu diambil (ready)
+func (h *Hold) IsActive() bool {
+	return h.Status == HoldStatusWaiting || h.Status == HoldStatusReady
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+type Loan struct {
+	ID         uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	User       User       `json:"-" gorm:"constraint:OnDelete:RESTRICT"`
+	BookID     uint       `json:"book_id" gorm:"not null;index"`
+	Book       Book       `json:"-" gorm:"constraint:OnDelete:RESTRICT"`
+	CopyID     *uint      `json:"copy_id" gorm:"index"`
+	Copy       *BookCopy  `json:"-" gorm:"constraint:OnDelete:SET NULL"`
+	BorrowedAt time.Time  `json:"borrowed_at" gorm:"not null"`
+	DueAt      time.Time  `json:"due_at" gorm:"not null"`
+	ReturnedAt *time.Time `json:"returned_at" gorm:"index"`
+	// RenewalCount adalah jumlah perpanjangan yang sudah dilakukan, riwayatnya di LoanRenewal
+	RenewalCount int       `json:"renewal_count" gorm:"not null;default:0"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// LoanRequest memilih eksemplar lewat copy_id atau barcode, atau cukup book_id untuk eksemplar tersedia pertama
+type LoanRequest struct {
+	BookID  uint   `json:"book_id"`
+	CopyID  uint   `json:"copy_id"`
+	Barcode string `json:"barcode"`
+	UserID  uint   `json:"user_id"`
+}
+
+type LoanResponse struct {
+	ID           uint          `json:"id"`
+	UserID       uint          `json:"user_id"`
+	BookID       uint          `json:"book_id"`
+	BookTitle    string        `json:"book_title,omitempty"`
+	CopyID       *uint         `json:"copy_id"`
+	Barcode      string        `json:"barcode,omitempty"`
+	BorrowedAt   time.Time     `json:"borrowed_at"`
+	DueAt        time.Time     `json:"due_at"`
+	ReturnedAt   *time.Time    `json:"returned_at"`
+	IsOverdue    bool          `json:"is_overdue"`
+	RenewalCount int           `json:"renewal_count"`
+	Renewals     []LoanRenewal `json:"renewals,omitempty"`
+}
+
+// LoanRenewal mencatat setiap perpanjangan loan untuk audit
+type LoanRenewal struct {
+	ID            uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	LoanID        uint      `json:"loan_id" gorm:"not null;index"`
+	Loan          Loan      `json:"-" gorm:"constraint:OnDelete:CASCADE"`
+	PreviousDueAt time.Time `json:"previous_due_at" gorm:"not null"`
+	NewDueAt      time.Time `json:"new_due_at" gorm:"not null"`
+	RenewedByID   uint      `json:"renewed_by_id" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// IsOverdue mengecek apakah pinjaman belum dikembalikan dan sudah lewat jatuh tempo
+func (l *Loan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && now.After(l.DueAt)
+}
+
+// DaysOverdue menghitung hari keterlambatan (dibulatkan ke atas) sampai waktu at
+func (l *Loan) DaysOverdue(at time.Time) int {
+	if !at.After(l.DueAt) {
+		return 0
+	}
+	return int(math.Ceil(at.Sub(l.DueAt).Hours() / 24))
+}
@@ -14,24 +14,29 @@ const (
 	RoleUser  UserRole = "user"
 )
 
+// IsValid mengecek apakah role termasuk role yang dikenal
+func (r UserRole) IsValid() bool {
+	return r == RoleAdmin || r == RoleUser
+}
+
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
 	Name      string         `json:"name" gorm:"type:varchar(255);not null"`
 	Email     string         `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
 	Password  string         `json:"-" gorm:"type:varchar(255);not null"`
-	Role      UserRole       `json:"role" gorm:"type:enum('admin','user');default:'user'"`
+	Role      UserRole       `json:"role" gorm:"type:varchar(20);default:'user';check:chk_users_role,role IN ('admin','user')"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	LastLogin *time.Time     `json:"last_login" gorm:"default:true"`
+	LastLogin *time.Time     `json:"last_login"`
 	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 }
 
 type UserRequest struct {
-	Name     string   `json:"name" validate:"required"`
-	Email    string   `json:"email" validate:"required,email"`
-	Password string   `json:"password" validate:"required,min=6"`
-	Role     UserRole `json:"role,omitempty"`
+	Name     string   `json:"name" validate:"required,max=255"`
+	Email    string   `json:"email" validate:"required,email,max=255"`
+	Password string   `json:"password" validate:"required,min=6,max=72"`
+	Role     UserRole `json:"role,omitempty" validate:"omitempty,oneof=admin user"`
 }
 
 type LoginRequest struct {
@@ -0,0 +1,208 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCopyNotFound  = apperror.NotFound("copy_not_found", "Eksemplar tidak ada")
+	ErrCopyOnLoan    = apperror.Conflict("copy_on_loan", "Eksemplar sedang dipinjam")
+	ErrCopyReserved  = apperror.Conflict("copy_reserved", "Eksemplar sedang disisihkan untuk hold")
+	ErrBarcodeExists = apperror.Conflict("barcode_exists", "Eksemplar dengan barcode sudah ada")
+)
+
+type BookCopyRepository struct {
+	DB *gorm.DB
+}
+
+func NewBookCopyRepository(db *gorm.DB) *BookCopyRepository {
+	return &BookCopyRepository{
+		DB: db,
+	}
+}
+
+// GetByBookID mengambil semua eksemplar milik book
+func (r *BookCopyRepository) GetByBookID(bookID uint) ([]models.BookCopy, error) {
+	var copies []models.BookCopy
+	err := r.DB.Where("book_id = ?", bookID).Order("id ASC").Find(&copies).Error
+	return copies, err
+}
+
+// GetByID mencari eksemplar by ID
+func (r *BookCopyRepository) GetByID(id uint) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	err := r.DB.First(&bookCopy, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrCopyNotFound)
+	}
+	return &bookCopy, nil
+}
+
+// GetByBarcode mencari eksemplar by barcode
+func (r *BookCopyRepository) GetByBarcode(barcode string) (*models.BookCopy, error) {
+	var bookCopy models.BookCopy
+	err := r.DB.Where("barcode = ?", barcode).First(&bookCopy).Error
+	if err != nil {
+		return nil, notFound(err, ErrCopyNotFound)
+	}
+	return &bookCopy, nil
+}
+
+// Create menambah eksemplar baru, barcode dibuat otomatis jika kosong
+func (r *BookCopyRepository) Create(bookCopy *models.BookCopy) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if bookCopy.Barcode == "" {
+			barcode, err := nextBarcode(tx, bookCopy.BookID)
+			if err != nil {
+				return err
+			}
+			bookCopy.Barcode = barcode
+		}
+
+		if err := tx.Create(bookCopy).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrBarcodeExists.Wrap(err)
+			}
+			return err
+		}
+		if err := refreshBook(tx, bookCopy.BookID); err != nil {
+			return err
+		}
+		return tx.First(bookCopy, bookCopy.ID).Error
+	})
+}
+
+// Update mengupdate data eksemplar (barcode, lokasi rak, kondisi)
+func (r *BookCopyRepository) Update(bookCopy *models.BookCopy) error {
+	err := r.DB.Save(bookCopy).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrBarcodeExists.Wrap(err)
+	}
+	return err
+}
+
+// UpdateStatus mengubah status satu eksemplar yang tidak sedang dipinjam
+func (r *BookCopyRepository) UpdateStatus(id uint, status string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var bookCopy models.BookCopy
+		if err := tx.First(&bookCopy, id).Error; err != nil {
+			return notFound(err, ErrCopyNotFound)
+		}
+
+		if err := ensureCopyNotOnLoan(tx, bookCopy.ID); err != nil {
+			return err
+		}
+		if bookCopy.Status == models.CopyStatusOnHold {
+			return ErrCopyReserved
+		}
+
+		if err := tx.Model(&bookCopy).Update("status", status).Error; err != nil {
+			return err
+		}
+		return refreshBook(tx, bookCopy.BookID)
+	})
+}
+
+// Delete menghapus eksemplar yang tidak sedang dipinjam
+func (r *BookCopyRepository) Delete(id uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var bookCopy models.BookCopy
+		if err := tx.First(&bookCopy, id).Error; err != nil {
+			return notFound(err, ErrCopyNotFound)
+		}
+
+		if err := ensureCopyNotOnLoan(tx, bookCopy.ID); err != nil {
+			return err
+		}
+		if bookCopy.Status == models.CopyStatusOnHold {
+			return ErrCopyReserved
+		}
+
+		if err := tx.Delete(&bookCopy).Error; err != nil {
+			return err
+		}
+		return syncBookStatus(tx, bookCopy.BookID)
+	})
+}
+
+// CheckBarcodeExists mengecek apakah barcode sudah dipakai
+func (r *BookCopyRepository) CheckBarcodeExists(barcode string, excludeID uint) (bool, error) {
+	var count int64
+	query := r.DB.Model(&models.BookCopy{}).Where("barcode = ?", barcode)
+
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+// activeLoanCopyIDs adalah subquery ID eksemplar yang sedang dipinjam
+func activeLoanCopyIDs(tx *gorm.DB) *gorm.DB {
+	return tx.Session(&gorm.Session{NewDB: true}).Model(&models.Loan{}).
+		Select("copy_id").
+		Where("returned_at IS NULL AND copy_id IS NOT NULL")
+}
+
+func ensureCopyNotOnLoan(tx *gorm.DB, copyID uint) error {
+	var count int64
+	err := tx.Model(&models.Loan{}).
+		Where("copy_id = ? AND returned_at IS NULL", copyID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrCopyOnLoan
+	}
+	return nil
+}
+
+// nextBarcode membuat barcode berurutan B<book id>-<nomor eksemplar> yang belum dipakai
+func nextBarcode(tx *gorm.DB, bookID uint) (string, error) {
+	var count int64
+	if err := tx.Model(&models.BookCopy{}).Where("book_id = ?", bookID).Count(&count).Error; err != nil {
+		return "", err
+	}
+
+	for seq := count + 1; ; seq++ {
+		barcode := fmt.Sprintf("B%06d-%03d", bookID, seq)
+
+		var exists int64
+		if err := tx.Model(&models.BookCopy{}).Where("barcode = ?", barcode).Count(&exists).Error; err != nil {
+			return "", err
+		}
+		if exists == 0 {
+			return barcode, nil
+		}
+	}
+}
+
+// syncBookStatus menyamakan kolom status Book dengan status eksemplarnya. Version book ikut naik
+// karena status dan jumlah eksemplar bagian dari representasi book, sehingga ETag lama tidak berlaku.
+func syncBookStatus(tx *gorm.DB, bookID uint) error {
+	status, err := bookStatus(tx, bookID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Book{}).Where("id = ?", bookID).Updates(map[string]interface{}{
+		"status":  status,
+		"version": gorm.Expr("version + 1"),
+	}).Error
+}
+
+// bookStatus menurunkan status book dari status semua eksemplarnya
+func bookStatus(tx *gorm.DB, bookID uint) (string, error) {
+	var statuses []string
+	if err := tx.Model(&models.BookCopy{}).Where("book_id = ?", bookID).Pluck("status", &statuses).Error; err != nil {
+		return "", err
+	}
+	return models.DeriveBookStatus(statuses), nil
+}
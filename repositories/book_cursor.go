@@ -0,0 +1,195 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = apperror.Validation("invalid_cursor", "Cursor tidak valid atau tidak cocok dengan sort")
+
+// CursorPagination adalah meta untuk pagination dengan cursor. NextCursor dan PrevCursor kosong
+// jika tidak ada halaman berikutnya atau sebelumnya.
+type CursorPagination struct {
+	Limit      int    `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// bookCursor adalah isi cursor sebelum di-encode: urutan yang dipakai, nilai kolom urutan dan id
+// book batas halaman, dan arah halaman. Klien hanya melihat bentuk base64-nya.
+type bookCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     uint     `json:"i"`
+	Prev   bool     `json:"p,omitempty"`
+}
+
+// sortKey menuliskan urutan dalam format ?sort=, untuk mengecek cursor dipakai dengan sort yang sama
+func sortKey(fields []SortField) string {
+	names := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if field.Desc {
+			names = append(names, "-"+field.Field)
+		} else {
+			names = append(names, field.Field)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// encodeCursor membuat cursor yang menunjuk book sebagai batas halaman. prev true untuk halaman
+// sebelum book, false untuk halaman sesudahnya.
+func encodeCursor(book *models.Book, order []SortField, prev bool) string {
+	cursor := bookCursor{Sort: sortKey(order), ID: book.ID, Prev: prev}
+	for _, field := range order {
+		var value string
+		switch v := sortValue(book, field.Field).(type) {
+		case string:
+			value = v
+		case int:
+			value = strconv.Itoa(v)
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		}
+		cursor.Values = append(cursor.Values, value)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor mengembalikan book batas halaman yang hanya berisi kolom urutan dan ID, beserta
+// arah halamannya. ErrInvalidCursor jika cursor rusak atau dibuat untuk sort lain.
+func decodeCursor(raw string, order []SortField) (boundary *models.Book, prev bool, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, false, ErrInvalidCursor
+	}
+	var cursor bookCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, false, ErrInvalidCursor
+	}
+	if cursor.Sort != sortKey(order) || len(cursor.Values) != len(order) {
+		return nil, false, ErrInvalidCursor
+	}
+
+	boundary = &models.Book{ID: cursor.ID}
+	for i, field := range order {
+		if err := setSortValue(boundary, field.Field, cursor.Values[i]); err != nil {
+			return nil, false, ErrInvalidCursor
+		}
+	}
+	return boundary, cursor.Prev, nil
+}
+
+// sortValue mengembalikan nilai kolom urutan milik book dengan tipe aslinya
+func sortValue(book *models.Book, field string) any {
+	switch field {
+	case "title":
+		return book.Title
+	case "author":
+		return book.Author
+	case "publisher":
+		return book.Publisher
+	case "total_pages":
+		return book.TotalPages
+	case "publisher_at":
+		return book.PublisherAt
+	case "created_at":
+		return book.CreatedAt
+	case "updated_at":
+		return book.UpdatedAt
+	}
+	return nil
+}
+
+func setSortValue(book *models.Book, field, raw string) error {
+	var err error
+	switch field {
+	case "title":
+		book.Title = raw
+	case "author":
+		book.Author = raw
+	case "publisher":
+		book.Publisher = raw
+	case "total_pages":
+		book.TotalPages, err = strconv.Atoi(raw)
+	case "publisher_at":
+		book.PublisherAt, err = time.Parse(time.RFC3339Nano, raw)
+	case "created_at":
+		book.CreatedAt, err = time.Parse(time.RFC3339Nano, raw)
+	case "updated_at":
+		book.UpdatedAt, err = time.Parse(time.RFC3339Nano, raw)
+	}
+	return err
+}
+
+// keysetCondition membuat syarat WHERE untuk book yang berada setelah boundary menurut keys,
+// misalnya untuk keys title, -created_at, -id:
+// title > ? OR (title = ? AND created_at < ?) OR (title = ? AND created_at = ? AND id < ?)
+func keysetCondition(boundary *models.Book, keys []SortField) (string, []any) {
+	value := func(field string) any {
+		if field == "id" {
+			return boundary.ID
+		}
+		return sortValue(boundary, field)
+	}
+
+	var clauses []string
+	var args []any
+	for i, key := range keys {
+		var parts []string
+		for _, equal := range keys[:i] {
+			parts = append(parts, equal.Field+" = ?")
+			args = append(args, value(equal.Field))
+		}
+		operator := " > ?"
+		if key.Desc {
+			operator = " < ?"
+		}
+		parts = append(parts, key.Field+operator)
+		args = append(args, value(key.Field))
+		clauses = append(clauses, "("+strings.Join(parts, " AND ")+")")
+	}
+	return strings.Join(clauses, " OR "), args
+}
+
+// reverseSort membalik arah setiap kolom, untuk mengambil halaman sebelum cursor
+func reverseSort(order []SortField) []SortField {
+	reversed := make([]SortField, len(order))
+	for i, field := range order {
+		reversed[i] = SortField{Field: field.Field, Desc: !field.Desc}
+	}
+	return reversed
+}
+
+// cursorPage memotong hasil query yang diambil limit+1 baris menjadi satu halaman dan membuat
+// cursor-nya. books urut sesuai arah pengambilan; untuk prev dibalik dulu ke urutan asli.
+func cursorPage(books []models.Book, order []SortField, limit int, hasCursor, prev bool) ([]models.Book, *CursorPagination) {
+	more := len(books) > limit
+	if more {
+		books = books[:limit]
+	}
+	if prev {
+		slices.Reverse(books)
+	}
+
+	page := &CursorPagination{Limit: limit}
+	if len(books) == 0 {
+		return books, page
+	}
+	// Halaman sebelum cursor selalu punya halaman sesudahnya, begitu juga sebaliknya
+	if more || prev {
+		page.NextCursor = encodeCursor(&books[len(books)-1], order, false)
+	}
+	if prev && more || !prev && hasCursor {
+		page.PrevCursor = encodeCursor(&books[0], order, true)
+	}
+	return books, page
+}
@@ -3,7 +3,13 @@ package repositories
 import (
 	"backend_perpustakaan_online/config"
 	"backend_perpustakaan_online/models"
-	"math"
+	"backend_perpustakaan_online/search"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
@@ -12,9 +18,9 @@ type BookRepository struct {
 	DB *gorm.DB
 }
 
-func NewBookRepository() *BookRepository {
+func NewBookRepository(db *gorm.DB) *BookRepository {
 	return &BookRepository{
-		DB: config.DB,
+		DB: db,
 	}
 }
 
@@ -26,11 +32,26 @@ type Pagination struct {
 }
 
 type BookFilter struct {
-	Search   string
-	Status   string
-	Category string
-	Page     int
-	Limit    int
+	Search string
+	// Statuses dan Categories cocok jika nilai book salah satu dari daftar
+	Statuses   []string
+	Categories []string
+	// Author dan Publisher dicocokkan utuh tanpa membedakan huruf besar kecil
+	Author    string
+	Publisher string
+	// MinPages dan MaxPages membatasi total_pages, 0 berarti tanpa batas
+	MinPages int
+	MaxPages int
+	// PublishedFrom dan PublishedTo membatasi tanggal publisher_at, keduanya inklusif
+	PublishedFrom *time.Time
+	PublishedTo   *time.Time
+	// CreatedSince dan UpdatedSince untuk klien yang menyinkronkan perubahan katalog
+	CreatedSince *time.Time
+	UpdatedSince *time.Time
+	// Sort kosong berarti yang terbaru dibuat di depan, atau urut relevansi untuk Search
+	Sort  []SortField
+	Page  int
+	Limit int
 }
 
 // GetAll books dengan pagination dan filter
@@ -38,52 +59,454 @@ func (r *BookRepository) GetAll(filter BookFilter) ([]models.Book, *Pagination,
 	var books []models.Book
 	var total int64
 
-	query := r.DB.Model(&models.Book{})
+	query := applyBookFilters(r.DB.Model(&models.Book{}), filter)
 
-	// Apply filters
-	if filter.Search != "" {
-		search := "%" + filter.Search + "%"
-		query = query.Where("title LIKE ? OR author LIKE ? OR isbn LIKE ?", search, search, search)
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
 	}
 
-	if filter.Status != "" {
-		query = query.Where("status = ?", filter.Status)
+	pagination, offset := paginate(filter.Page, filter.Limit, total)
+
+	// Execute query dengan pagination
+	err := query.Offset(offset).Limit(pagination.Limit).Order(orderClause(sortKeys(bookSort(filter)))).Find(&books).Error
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if filter.Category != "" {
-		query = query.Where("category = ?", filter.Category)
+	return books, pagination, nil
+}
+
+// GetAllByCursor memakai keyset: syarat WHERE pada kolom urutan menggantikan OFFSET dan COUNT,
+// sehingga tetap cepat di katalog besar dan tidak melompati atau mengulang book yang berubah di
+// antara request
+func (r *BookRepository) GetAllByCursor(filter BookFilter, cursor string) ([]models.Book, *CursorPagination, error) {
+	order := bookSort(filter)
+	keys := sortKeys(order)
+	query := applyBookFilters(r.DB.Model(&models.Book{}), filter)
+
+	prev := false
+	if cursor != "" {
+		boundary, isPrev, err := decodeCursor(cursor, order)
+		if err != nil {
+			return nil, nil, err
+		}
+		prev = isPrev
+		// Halaman sebelumnya diambil dengan urutan terbalik lalu dibalik lagi oleh cursorPage
+		if prev {
+			keys = reverseSort(keys)
+		}
+		condition, args := keysetCondition(boundary, keys)
+		query = query.Where(condition, args...)
 	}
 
-	// Count total records
-	if err := query.Count(&total).Error; err != nil {
+	limit := normalizeLimit(filter.Limit)
+	var books []models.Book
+	if err := query.Order(orderClause(keys)).Limit(limit + 1).Find(&books).Error; err != nil {
 		return nil, nil, err
 	}
 
-	// Calculate pagination
-	if filter.Page < 1 {
-		filter.Page = 1
+	books, page := cursorPage(books, order, limit, cursor != "", prev)
+	return books, page, nil
+}
+
+// Search mencari lewat inverted index book_search_terms. Kecocokan dan filter dicek di database
+// dengan subquery, posting hanya diambil untuk book yang cocok lalu diranking di Go, dan hanya book
+// di halaman yang diminta yang diambil dari tabel books.
+func (r *BookRepository) Search(filter BookFilter) (*SearchResult, error) {
+	s, err := r.prepareSearch(filter.Search)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := r.rank(s, filter)
+	if err != nil {
+		return nil, err
 	}
-	if filter.Limit < 1 {
-		filter.Limit = 10
+
+	pagination, offset := paginate(filter.Page, filter.Limit, int64(len(hits)))
+	var ids []uint
+	if len(filter.Sort) == 0 {
+		ids = hitIDs(pageOf(hits, offset, pagination.Limit))
+	} else {
+		condition, args := r.matchCondition(s)
+		err := applyBookFilters(r.DB.Model(&models.Book{}), filter).
+			Where(condition, args...).
+			Order(orderClause(sortKeys(filter.Sort))).
+			Offset(offset).
+			Limit(pagination.Limit).
+			Pluck("id", &ids).Error
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	offset := (filter.Page - 1) * filter.Limit
-	totalPage := int(math.Ceil(float64(total) / float64(filter.Limit)))
+	books, err := r.findBooks(ids)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResult{
+		Hits:       bookHits(s.query, ids, books, hits),
+		Pagination: pagination,
+		DidYouMean: s.query.DidYouMean(),
+	}, nil
+}
 
-	pagination := &Pagination{
-		Page:      filter.Page,
-		Limit:     filter.Limit,
-		Total:     total,
-		TotalPage: totalPage,
+// Suggest meranking seperti Search lalu mengambil judul dan penulis dari book teratas
+func (r *BookRepository) Suggest(text string, limit int) ([]Suggestion, error) {
+	s, err := r.prepareSearch(text)
+	if err != nil {
+		return nil, err
+	}
+	hits, err := r.rank(s, BookFilter{})
+	if err != nil {
+		return nil, err
 	}
 
-	// Execute query dengan pagination
-	err := query.Offset(offset).Limit(filter.Limit).Order("created_at DESC").Find(&books).Error
+	hits = hits[:min(len(hits), suggestCandidates)]
+	books, err := r.findBooks(hitIDs(hits))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	return suggestions(s.query, hits, books, limit), nil
+}
+
+// findBooks mengambil book dengan ids, dipetakan by ID. ids paling banyak satu halaman.
+func (r *BookRepository) findBooks(ids []uint) (map[uint]models.Book, error) {
+	books := map[uint]models.Book{}
+	if len(ids) == 0 {
+		return books, nil
 	}
 
-	return books, pagination, nil
+	var found []models.Book
+	if err := r.DB.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, book := range found {
+		books[book.ID] = book
+	}
+	return books, nil
+}
+
+// prepareSearch mencari term indeks untuk setiap term query: kata utuh, awalan term terakhir yang
+// paling sering muncul, dan koreksi dari term judul dan penulis untuk term yang tidak ditemukan.
+// Pencarian ISBN yang menemukan book tidak dikoreksi.
+func (r *BookRepository) prepareSearch(text string) (*bookSearch, error) {
+	s := newBookSearch(text)
+	query := s.query
+	if query.Empty() {
+		return s, nil
+	}
+
+	var indexed, prefixed []string
+	err := r.DB.Model(&models.BookSearchTerm{}).Where("term IN ?", query.Terms).Distinct("term").Pluck("term", &indexed).Error
+	if err != nil {
+		return nil, err
+	}
+	// Term hanya berisi huruf dan angka, jadi aman dipakai di LIKE tanpa escape
+	err = r.DB.Model(&models.BookSearchTerm{}).
+		Where("term LIKE ?", query.Prefix()+"%").
+		Group("term").
+		Order("COUNT(*) DESC, term").
+		Limit(maxPrefixTerms).
+		Pluck("term", &prefixed).Error
+	if err != nil {
+		return nil, err
+	}
+	indexed = append(indexed, prefixed...)
+
+	if missing := query.Missing(indexed); len(missing) > 0 {
+		var isbnIDs []uint
+		if s.fullISBN {
+			err := r.DB.Model(&models.Book{}).Where("isbn LIKE ?", "%"+s.isbn+"%").Limit(1).Pluck("id", &isbnIDs).Error
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if correctable := query.Correctable(missing); len(isbnIDs) == 0 && len(correctable) > 0 {
+			condition, args := r.candidateCondition(query, correctable)
+			var vocabulary []string
+			err := r.DB.Model(&models.BookSearchTerm{}).
+				Where("field IN ?", fuzzyFields).
+				Where(condition, args...).
+				Distinct("term").
+				Pluck("term", &vocabulary).Error
+			if err != nil {
+				return nil, err
+			}
+			query = query.Correct(missing, vocabulary)
+			indexed = append(indexed, query.Corrections()...)
+		}
+	}
+
+	s.complete(query, indexed)
+	return s, nil
+}
+
+// candidateCondition menyaring vocabulary untuk koreksi salah ketik di database: hanya term yang
+// panjangnya dalam batas MaxEdits dari term query dan berawalan StartLetters-nya yang diambil.
+func (r *BookRepository) candidateCondition(query search.Query, correctable []int) (string, []any) {
+	// LENGTH di MySQL menghitung byte, sedangkan term bisa berisi huruf multibyte
+	length := "LENGTH(term)"
+	if r.DB.Dialector.Name() == config.DriverMySQL {
+		length = "CHAR_LENGTH(term)"
+	}
+
+	var conditions []string
+	var args []any
+	for _, i := range correctable {
+		term := query.Terms[i]
+		n, edits := utf8.RuneCountInString(term), search.MaxEdits(term)
+		args = append(args, n-edits, n+edits)
+
+		var starts []string
+		for _, start := range search.StartLetters(term) {
+			starts = append(starts, "term LIKE ?")
+			args = append(args, start+"%")
+		}
+		conditions = append(conditions, "("+length+" BETWEEN ? AND ? AND ("+strings.Join(starts, " OR ")+"))")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// matchCondition membuat syarat WHERE pada tabel books untuk book yang cocok dengan pencarian:
+// setiap term query dipenuhi salah satu term book di book_search_terms, atau ISBN-nya mengandung
+// angka yang dicari. Semua dicek dengan subquery sehingga jumlah parameter tidak bergantung pada
+// jumlah book yang cocok.
+func (r *BookRepository) matchCondition(s *bookSearch) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if groups := s.termGroups(); groups != nil {
+		// Setiap term indeks dipetakan ke indeks term query-nya; book cocok jika semua indeks muncul
+		cases := "CASE"
+		var caseArgs []any
+		for i, group := range groups {
+			cases += " WHEN term IN ? THEN " + strconv.Itoa(i)
+			caseArgs = append(caseArgs, group)
+		}
+		cases += " END"
+
+		matched := r.DB.Model(&models.BookSearchTerm{}).
+			Select("book_id").
+			Where("term IN ?", s.terms).
+			Group("book_id").
+			Having("COUNT(DISTINCT "+cases+") = ?", append(caseArgs, len(groups))...)
+		conditions = append(conditions, "id IN (?)")
+		args = append(args, matched)
+	}
+	if s.isbn != "" {
+		conditions = append(conditions, "isbn LIKE ?")
+		args = append(args, "%"+s.isbn+"%")
+	}
+
+	if len(conditions) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// rank meranking semua book yang lolos filter dan cocok dengan pencarian. Posting yang diambil
+// hanya milik book tersebut untuk term di s.terms.
+func (r *BookRepository) rank(s *bookSearch, filter BookFilter) ([]search.Hit, error) {
+	condition, args := r.matchCondition(s)
+
+	var postings []search.Posting
+	if len(s.terms) > 0 {
+		matched := applyBookFilters(r.DB.Model(&models.Book{}).Select("id"), filter).Where(condition, args...)
+		found, err := r.findPostings(r.DB.Where("term IN ?", s.terms).Where("book_id IN (?)", matched))
+		if err != nil {
+			return nil, err
+		}
+		postings = found
+	}
+
+	var isbnIDs []uint
+	if s.isbn != "" {
+		err := applyBookFilters(r.DB.Model(&models.Book{}), filter).
+			Where("isbn LIKE ?", "%"+s.isbn+"%").
+			Pluck("id", &isbnIDs).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var totalDocs int64
+	if err := r.DB.Model(&models.Book{}).Count(&totalDocs).Error; err != nil {
+		return nil, err
+	}
+
+	return rankBooks(s.query, postings, int(totalDocs), isbnIDs, s.fullISBN), nil
+}
+
+// findPostings mengambil baris book_search_terms yang cocok dengan condition sebagai posting
+func (r *BookRepository) findPostings(condition *gorm.DB) ([]search.Posting, error) {
+	var terms []models.BookSearchTerm
+	if err := r.DB.Where(condition).Find(&terms).Error; err != nil {
+		return nil, err
+	}
+
+	postings := make([]search.Posting, 0, len(terms))
+	for _, term := range terms {
+		postings = append(postings, search.Posting{DocID: term.BookID, Term: term.Term, Field: term.Field, Frequency: term.Frequency})
+	}
+	return postings, nil
+}
+
+// Facets menghitung facet dengan GROUP BY di database. Untuk pencarian, hitungan dibatasi ke book
+// yang cocok dengan teks pencarian lewat subquery yang sama dengan Search, tanpa ranking.
+func (r *BookRepository) Facets(filter BookFilter, names []string) (map[string][]FacetCount, error) {
+	query := applyBookFilters(r.DB.Model(&models.Book{}), filter)
+	if filter.Search != "" {
+		s, err := r.prepareSearch(filter.Search)
+		if err != nil {
+			return nil, err
+		}
+		condition, args := r.matchCondition(s)
+		query = query.Where(condition, args...)
+	}
+
+	facets := make(map[string][]FacetCount, len(names))
+	for _, name := range names {
+		column, present := r.facetColumn(name)
+		var counts []FacetCount
+		err := query.Session(&gorm.Session{}).
+			Select(column + " AS value, COUNT(*) AS count").
+			Where(present).
+			Group("value").
+			Order("count DESC, value").
+			Limit(facetLimit).
+			Scan(&counts).Error
+		if err != nil {
+			return nil, err
+		}
+		if name == FacetDecade {
+			for i := range counts {
+				counts[i].Value += "s"
+			}
+		}
+		facets[name] = append([]FacetCount{}, counts...)
+	}
+	return facets, nil
+}
+
+// facetColumn mengembalikan ekspresi SQL nilai facet dan syarat baris yang punya nilai. Dekade
+// dihitung dari tahun publisher_at yang caranya berbeda di setiap driver; tanggal kosong
+// (zero time) tidak dihitung.
+func (r *BookRepository) facetColumn(name string) (column, present string) {
+	if name != FacetDecade {
+		return name, name + " IS NOT NULL AND " + name + " <> ''"
+	}
+
+	present = "publisher_at IS NOT NULL AND publisher_at >= '1000-01-01'"
+	switch r.DB.Dialector.Name() {
+	case config.DriverMySQL:
+		return "FLOOR(YEAR(publisher_at) / 10) * 10", present
+	case config.DriverPostgres:
+		return "CAST(FLOOR(EXTRACT(YEAR FROM publisher_at) / 10) * 10 AS integer)", present
+	}
+	return "CAST(substr(publisher_at, 1, 4) AS integer) / 10 * 10", present
+}
+
+// IndexMissing mengindeks book yang belum ada di book_search_terms, yaitu book dari sebelum
+// pencarian full-text ada dan book yang dibuat Seeder. Book di trash ikut diindeks supaya langsung
+// bisa dicari setelah di-restore.
+func (r *BookRepository) IndexMissing() (int, error) {
+	var books []models.Book
+	err := r.DB.Unscoped().
+		Where("id NOT IN (?)", r.DB.Model(&models.BookSearchTerm{}).Select("book_id")).
+		Find(&books).Error
+	if err != nil || len(books) == 0 {
+		return 0, err
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range books {
+			if err := indexBook(tx, &books[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(books), nil
+}
+
+// indexBook mengganti isi inverted index pencarian untuk book
+func indexBook(tx *gorm.DB, book *models.Book) error {
+	if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookSearchTerm{}).Error; err != nil {
+		return err
+	}
+
+	postings := bookDocument(book).Postings()
+	if len(postings) == 0 {
+		return nil
+	}
+	terms := make([]models.BookSearchTerm, 0, len(postings))
+	for _, posting := range postings {
+		terms = append(terms, models.BookSearchTerm{
+			BookID:    book.ID,
+			Field:     posting.Field,
+			Term:      posting.Term,
+			Frequency: posting.Frequency,
+		})
+	}
+	return tx.CreateInBatches(terms, 100).Error
+}
+
+// applyBookFilters menerapkan semua filter BookFilter selain Search ke query book
+func applyBookFilters(query *gorm.DB, filter BookFilter) *gorm.DB {
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if len(filter.Categories) > 0 {
+		query = query.Where("category IN ?", filter.Categories)
+	}
+	if filter.Author != "" {
+		query = query.Where("LOWER(author) = LOWER(?)", filter.Author)
+	}
+	if filter.Publisher != "" {
+		query = query.Where("LOWER(publisher) = LOWER(?)", filter.Publisher)
+	}
+	if filter.MinPages > 0 {
+		query = query.Where("total_pages >= ?", filter.MinPages)
+	}
+	if filter.MaxPages > 0 {
+		query = query.Where("total_pages <= ?", filter.MaxPages)
+	}
+	if filter.PublishedFrom != nil {
+		query = query.Where("publisher_at >= ?", *filter.PublishedFrom)
+	}
+	if filter.PublishedTo != nil {
+		query = query.Where("publisher_at < ?", filter.PublishedTo.AddDate(0, 0, 1))
+	}
+	// SQLite membandingkan waktu sebagai teks, jadi zona waktunya disamakan dengan waktu yang
+	// disimpan aplikasi
+	if filter.CreatedSince != nil {
+		query = query.Where("created_at >= ?", filter.CreatedSince.Local())
+	}
+	if filter.UpdatedSince != nil {
+		query = query.Where("updated_at >= ?", filter.UpdatedSince.Local())
+	}
+	return query
+}
+
+// orderClause mengubah urutan ke ORDER BY, kolom di luar BookSortFields dan id diabaikan
+func orderClause(keys []SortField) string {
+	var clauses []string
+	for _, key := range keys {
+		if key.Field != "id" && !slices.Contains(BookSortFields, key.Field) {
+			continue
+		}
+		direction := " ASC"
+		if key.Desc {
+			direction = " DESC"
+		}
+		clauses = append(clauses, key.Field+direction)
+	}
+	return strings.Join(clauses, ", ")
 }
 
 // GetByID mencari book by ID
@@ -91,45 +514,269 @@ func (r *BookRepository) GetByID(id uint) (*models.Book, error) {
 	var book models.Book
 	err := r.DB.First(&book, id).Error
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, ErrBookNotFound)
 	}
 	return &book, nil
 }
 
-// GetByISBN mencari book by ISBN
+// GetByISBN mencari book by ISBN-10 atau ISBN-13
 func (r *BookRepository) GetByISBN(isbn string) (*models.Book, error) {
 	var book models.Book
-	err := r.DB.Where("isbn = ?", isbn).First(&book).Error
+	err := r.DB.Where("isbn = ?", canonicalISBN(isbn)).First(&book).Error
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, ErrBookNotFound)
 	}
 	return &book, nil
 }
 
-// Create membuat book baru
+// Create membuat book baru beserta eksemplar awal di book.Copies dan indeks pencariannya dalam
+// satu transaksi
 func (r *BookRepository) Create(book *models.Book) error {
-	return r.DB.Create(book).Error
+	book.ISBN = canonicalISBN(book.ISBN)
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		copies := book.Copies
+		if err := tx.Omit("Copies").Create(book).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrISBNExists.Wrap(err)
+			}
+			return err
+		}
+
+		for i := range copies {
+			copies[i].BookID = book.ID
+			if copies[i].Barcode == "" {
+				barcode, err := nextBarcode(tx, book.ID)
+				if err != nil {
+					return err
+				}
+				copies[i].Barcode = barcode
+			}
+			if err := tx.Create(&copies[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := syncBookStatus(tx, book.ID); err != nil {
+			return err
+		}
+		if err := tx.First(book, book.ID).Error; err != nil {
+			return err
+		}
+		return indexBook(tx, book)
+	})
 }
 
-// Update mengupdate book
-func (r *BookRepository) Update(book *models.Book) error {
-	return r.DB.Save(book).Error
+// Update mengupdate semua kolom book dengan optimistic locking pada kolom version, lalu mengindeks
+// ulang book untuk pencarian dalam transaksi yang sama
+func (r *BookRepository) Update(book *models.Book, status string) error {
+	book.ISBN = canonicalISBN(book.ISBN)
+	version, previousStatus := book.Version, book.Status
+	book.Version++
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Status book diturunkan dari eksemplar, jadi status baru diterapkan ke eksemplar lebih dulu
+		// lalu ikut disimpan bersama field lain dengan cek version yang sama
+		if status != "" {
+			derived, err := applyCopyStatus(tx, book.ID, status)
+			if err != nil {
+				return err
+			}
+			book.Status = derived
+		}
+
+		result := tx.Model(book).Where("version = ?", version).
+			Select("*").Omit("ID", "Copies", "CreatedAt", "DeletedAt").
+			Updates(book)
+		switch {
+		case errors.Is(result.Error, gorm.ErrDuplicatedKey):
+			return ErrISBNExists.Wrap(result.Error)
+		case result.Error != nil:
+			return result.Error
+		case result.RowsAffected == 0:
+			return bookModified(tx, book.ID)
+		}
+		return indexBook(tx, book)
+	})
+	if err != nil {
+		book.Version, book.Status = version, previousStatus
+	}
+	return err
 }
 
-// Delete menghapus book (soft delete)
-func (r *BookRepository) Delete(id uint) error {
-	return r.DB.Delete(&models.Book{}, id).Error
+// bookModified menjelaskan kenapa update bersyarat version tidak mengenai baris: ErrBookNotFound jika
+// book sudah tidak ada atau di trash, ErrBookModified jika version-nya sudah berubah
+func bookModified(tx *gorm.DB, id uint) error {
+	var count int64
+	if err := tx.Model(&models.Book{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrBookNotFound
+	}
+	return ErrBookModified
 }
 
-// UpdateStatus mengupdate status book
-func (r *BookRepository) UpdateStatus(id uint, status string) error {
-	return r.DB.Model(&models.Book{}).Where("id = ?", id).Update("status", status).Error
+// Delete memindahkan book ke trash (soft delete) jika version-nya masih sama. Hold aktif dibatalkan
+// dan eksemplar yang disisihkan untuk hold dikembalikan ke rak.
+func (r *BookRepository) Delete(id, version uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		var onLoan int64
+		err := tx.Model(&models.Loan{}).
+			Where("book_id = ? AND returned_at IS NULL", id).
+			Count(&onLoan).Error
+		if err != nil {
+			return err
+		}
+		if onLoan > 0 {
+			return ErrBookOnLoan
+		}
+
+		err = tx.Model(&models.Hold{}).
+			Where("book_id = ? AND status IN ?", id, activeHoldStatuses).
+			Updates(map[string]interface{}{
+				"status":    models.HoldStatusCancelled,
+				"copy_id":   nil,
+				"closed_at": time.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.BookCopy{}).
+			Where("book_id = ? AND status = ?", id, models.CopyStatusOnHold).
+			Update("status", models.CopyStatusAvailable).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("version = ?", version).Delete(&models.Book{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return bookModified(tx, id)
+		}
+		return nil
+	})
 }
 
-// CheckISBNExists mengecek apakah ISBN sudah ada
+// GetDeleted books di trash dengan pagination, yang terakhir dihapus di depan
+func (r *BookRepository) GetDeleted(page, limit int) ([]models.Book, *Pagination, error) {
+	var books []models.Book
+	var total int64
+
+	query := r.DB.Unscoped().Model(&models.Book{}).Where("deleted_at IS NOT NULL")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	pagination, offset := paginate(page, limit, total)
+
+	err := query.Offset(offset).Limit(pagination.Limit).Order("deleted_at DESC").Find(&books).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return books, pagination, nil
+}
+
+// GetDeletedByID mencari book di trash by ID
+func (r *BookRepository) GetDeletedByID(id uint) (*models.Book, error) {
+	var book models.Book
+	err := r.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&book, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrBookNotInTrash)
+	}
+	return &book, nil
+}
+
+// Restore mengeluarkan book dari trash
+func (r *BookRepository) Restore(id uint) error {
+	result := r.DB.Unscoped().Model(&models.Book{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBookNotInTrash
+	}
+	return nil
+}
+
+// Purge menghapus permanen book yang sudah di trash sejak sebelum deletedBefore. Book yang punya
+// riwayat loan tidak ikut dihapus supaya riwayat sirkulasi dan denda tetap utuh.
+func (r *BookRepository) Purge(deletedBefore time.Time) (purged int64, skipped int64, err error) {
+	base := r.DB.Unscoped().Model(&models.Book{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore)
+
+	var candidates int64
+	if err := base.Session(&gorm.Session{}).Count(&candidates).Error; err != nil {
+		return 0, 0, err
+	}
+
+	result := base.Session(&gorm.Session{}).
+		Where("id NOT IN (?)", r.DB.Model(&models.Loan{}).Select("book_id")).
+		Delete(&models.Book{})
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+
+	return result.RowsAffected, candidates - result.RowsAffected, nil
+}
+
+// UpdateStatus mengubah status semua eksemplar book yang tidak sedang dipinjam, disisihkan untuk
+// hold, atau hilang, lalu status book dihitung ulang dari eksemplarnya. Seperti Update, perubahan
+// dibatalkan dengan ErrBookModified jika version book sudah berubah.
+func (r *BookRepository) UpdateStatus(book *models.Book, status string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		derived, err := applyCopyStatus(tx, book.ID, status)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Book{}).
+			Where("id = ? AND version = ?", book.ID, book.Version).
+			Updates(map[string]interface{}{
+				"status":  derived,
+				"version": book.Version + 1,
+			})
+		switch {
+		case result.Error != nil:
+			return result.Error
+		case result.RowsAffected == 0:
+			return bookModified(tx, book.ID)
+		}
+		return tx.First(book, book.ID).Error
+	})
+}
+
+// applyCopyStatus mengubah status eksemplar book yang tidak sedang dipinjam, disisihkan untuk hold,
+// atau hilang, lalu meneruskan eksemplar tersedia ke antrean hold. Mengembalikan status book
+// turunannya tanpa menyimpannya.
+func applyCopyStatus(tx *gorm.DB, bookID uint, status string) (string, error) {
+	err := tx.Model(&models.BookCopy{}).
+		Where("book_id = ? AND status NOT IN ?", bookID, []string{models.CopyStatusOnHold, models.CopyStatusLost}).
+		Where("id NOT IN (?)", activeLoanCopyIDs(tx)).
+		Update("status", status).Error
+	if err != nil {
+		return "", err
+	}
+	if err := promoteHolds(tx, bookID, time.Now()); err != nil {
+		return "", err
+	}
+	return bookStatus(tx, bookID)
+}
+
+// CheckISBNExists mengecek apakah ISBN sudah ada, termasuk book di trash karena unique index ISBN
+// tetap berlaku untuk baris yang di-soft delete
 func (r *BookRepository) CheckISBNExists(isbn string, excludeID uint) (bool, error) {
 	var count int64
-	query := r.DB.Model(&models.Book{}).Where("isbn = ?", isbn)
+	query := r.DB.Unscoped().Model(&models.Book{}).Where("isbn = ?", canonicalISBN(isbn))
 
 	if excludeID > 0 {
 		query = query.Where("id != ?", excludeID)
@@ -138,3 +785,30 @@ func (r *BookRepository) CheckISBNExists(isbn string, excludeID uint) (bool, err
 	err := query.Count(&count).Error
 	return count > 0, err
 }
+
+// CountCopies menghitung total dan eksemplar tersedia untuk setiap book
+func (r *BookRepository) CountCopies(bookIDs []uint) (map[uint]models.CopyCounts, error) {
+	counts := make(map[uint]models.CopyCounts, len(bookIDs))
+	if len(bookIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		BookID    uint
+		Total     int
+		Available int
+	}
+	err := r.DB.Model(&models.BookCopy{}).
+		Select("book_id, COUNT(*) AS total, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS available", models.CopyStatusAvailable).
+		Where("book_id IN ?", bookIDs).
+		Group("book_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.BookID] = models.CopyCounts{Total: row.Total, Available: row.Available}
+	}
+	return counts, nil
+}
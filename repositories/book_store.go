@@ -0,0 +1,489 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/isbn"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/search"
+	"cmp"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBookNotFound   = apperror.NotFound("book_not_found", "Buku tidak ada")
+	ErrBookNotInTrash = apperror.NotFound("book_not_in_trash", "Buku tidak ada di trash")
+	ErrBookOnLoan     = apperror.Conflict("book_on_loan", "Buku masih dipinjam, kembalikan semua eksemplar sebelum dihapus")
+	ErrISBNExists     = apperror.Conflict("isbn_exists", "Buku dengan ISBN sudah ada")
+	ErrBookModified   = apperror.PreconditionFailed("book_modified", "Buku sudah diubah oleh request lain, ambil ulang data terbaru")
+)
+
+// BookStore adalah penyimpanan katalog buku yang dipakai BookHandler. BookRepository menyimpan ke
+// database, MemoryBookStore menyimpan di memori untuk test dan demo mode.
+type BookStore interface {
+	// GetAll mengambil book yang lolos filter; filter.Search tidak dipakai, pencarian teks lewat Search
+	GetAll(filter BookFilter) ([]models.Book, *Pagination, error)
+	// GetAllByCursor seperti GetAll tanpa COUNT dan offset: halaman dimulai setelah, atau sebelum
+	// untuk cursor prev, book yang ditunjuk cursor. cursor kosong berarti halaman pertama.
+	// filter.Search dan filter.Page tidak dipakai.
+	GetAllByCursor(filter BookFilter, cursor string) ([]models.Book, *CursorPagination, error)
+	// Search mencari filter.Search di judul, penulis, penerbit, deskripsi, dan ISBN, urut dari yang
+	// paling relevan. Filter lain tetap berlaku. Kata yang salah ketik dicocokkan dengan kata
+	// paling mirip di judul dan penulis.
+	Search(filter BookFilter) (*SearchResult, error)
+	// Suggest mengembalikan paling banyak limit judul dan penulis yang cocok dengan text untuk
+	// autocomplete, dengan toleransi salah ketik yang sama dengan Search
+	Suggest(text string, limit int) ([]Suggestion, error)
+	// Facets menghitung jumlah book per nilai untuk setiap facet di names, atas semua book yang lolos
+	// filter termasuk pencarian, bukan hanya satu halaman
+	Facets(filter BookFilter, names []string) (map[string][]FacetCount, error)
+	// GetByID mencari book by ID, ErrBookNotFound jika tidak ada atau sudah di trash
+	GetByID(id uint) (*models.Book, error)
+	// GetByISBN mencari book by ISBN dalam bentuk ISBN-10 maupun ISBN-13
+	GetByISBN(isbn string) (*models.Book, error)
+	// Create membuat book beserta eksemplar awal di book.Copies, ErrISBNExists jika ISBN sudah dipakai.
+	// ISBN disimpan dalam bentuk kanonik ISBN-13 tanpa tanda hubung, begitu juga di Update.
+	Create(book *models.Book) error
+	// Update menyimpan book hanya jika version tersimpan masih sama dengan book.Version, lalu
+	// menaikkan book.Version. ErrBookModified jika book sudah diubah request lain, ErrBookNotFound
+	// jika book ada di trash. Jika status diisi, status itu diterapkan ke eksemplar seperti
+	// UpdateStatus dalam transaksi yang sama dan book.Status diisi status turunannya.
+	Update(book *models.Book, status string) error
+	// Delete memindahkan book ke trash jika version tersimpan masih sama, ErrBookModified jika book
+	// sudah diubah request lain dan ErrBookOnLoan jika masih ada eksemplar dipinjam
+	Delete(id, version uint) error
+	// UpdateStatus menerapkan status ke eksemplar yang tidak sedang dipinjam atau hilang lalu menghitung
+	// ulang status book, dengan cek version seperti Update. book diisi data terbaru.
+	UpdateStatus(book *models.Book, status string) error
+	// CheckISBNExists menerima ISBN-10 maupun ISBN-13, keduanya dicocokkan ke bentuk kanonik
+	CheckISBNExists(isbn string, excludeID uint) (bool, error)
+	// CountCopies menghitung total dan eksemplar tersedia untuk setiap book
+	CountCopies(bookIDs []uint) (map[uint]models.CopyCounts, error)
+
+	GetDeleted(page, limit int) ([]models.Book, *Pagination, error)
+	// GetDeletedByID mencari book di trash by ID, ErrBookNotInTrash jika tidak ada
+	GetDeletedByID(id uint) (*models.Book, error)
+	// Restore mengeluarkan book dari trash, ErrBookNotInTrash jika book tidak ada di trash
+	Restore(id uint) error
+	Purge(deletedBefore time.Time) (purged int64, skipped int64, err error)
+}
+
+// BookHit adalah book hasil Search beserta skor relevansi dan potongan teks per field dengan kata
+// yang cocok dibungkus <mark>
+type BookHit struct {
+	models.Book
+	Score      float64
+	Highlights map[string]string
+}
+
+// Facet yang bisa diminta lewat Facets
+const (
+	FacetCategory  = "category"
+	FacetStatus    = "status"
+	FacetAuthor    = "author"
+	FacetPublisher = "publisher"
+	// FacetDecade adalah dekade terbit dari publisher_at, misalnya "1920s"
+	FacetDecade = "decade"
+)
+
+var FacetNames = []string{FacetCategory, FacetStatus, FacetAuthor, FacetPublisher, FacetDecade}
+
+// facetLimit adalah jumlah maksimum nilai per facet, yang paling banyak book-nya di depan
+const facetLimit = 20
+
+// FacetCount adalah jumlah book dengan nilai Value pada sebuah facet
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// SortField adalah satu kolom pengurutan, Desc untuk urutan menurun
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// BookSortFields adalah kolom yang boleh dipakai untuk mengurutkan book
+var BookSortFields = []string{"title", "author", "publisher", "publisher_at", "total_pages", "created_at", "updated_at"}
+
+// bookSort adalah urutan filter, atau yang terbaru dibuat di depan jika kosong
+func bookSort(filter BookFilter) []SortField {
+	if len(filter.Sort) == 0 {
+		return []SortField{{Field: "created_at", Desc: true}}
+	}
+	return filter.Sort
+}
+
+// sortKeys menambahkan id menurun sebagai pengurut terakhir supaya urutan stabil antar halaman
+func sortKeys(order []SortField) []SortField {
+	return append(append([]SortField{}, order...), SortField{Field: "id", Desc: true})
+}
+
+// compareBooks membandingkan dua book menurut fields dengan id menurun sebagai pengurut terakhir,
+// sama dengan sortKeys
+func compareBooks(a, b *models.Book, fields []SortField) int {
+	for _, field := range fields {
+		var c int
+		switch field.Field {
+		case "title":
+			c = strings.Compare(a.Title, b.Title)
+		case "author":
+			c = strings.Compare(a.Author, b.Author)
+		case "publisher":
+			c = strings.Compare(a.Publisher, b.Publisher)
+		case "publisher_at":
+			c = a.PublisherAt.Compare(b.PublisherAt)
+		case "total_pages":
+			c = cmp.Compare(a.TotalPages, b.TotalPages)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case "updated_at":
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		if field.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
+
+// SearchResult adalah satu halaman hasil Search. DidYouMean berisi teks pencarian dengan kata yang
+// salah ketik diganti, kosong jika semua kata ditemukan.
+type SearchResult struct {
+	Hits       []BookHit
+	Pagination *Pagination
+	DidYouMean string
+}
+
+// Suggestion adalah satu saran autocomplete: judul book atau nama penulis
+type Suggestion struct {
+	Text  string `json:"text"`
+	Field string `json:"field"`
+	// Highlight adalah Text dengan kata yang cocok dibungkus <mark>
+	Highlight string `json:"highlight"`
+	// BookID diisi untuk saran judul, BookCount untuk saran penulis
+	BookID    uint `json:"book_id,omitempty"`
+	BookCount int  `json:"book_count,omitempty"`
+}
+
+const (
+	// MaxSuggestions adalah batas atas jumlah saran Suggest
+	MaxSuggestions = 20
+	// suggestCandidates adalah jumlah book teratas yang diambil judul dan penulisnya oleh Suggest
+	suggestCandidates = 100
+)
+
+// fuzzyFields adalah field yang kata-katanya dipakai untuk mengoreksi salah ketik
+var fuzzyFields = []string{search.FieldTitle, search.FieldAuthor}
+
+// snippetLength adalah panjang maksimum potongan teks di BookHit.Highlights
+const snippetLength = 160
+
+// maxPrefixTerms adalah jumlah maksimum term indeks yang dicocokkan dengan awalan term terakhir,
+// diambil yang paling sering muncul, supaya awalan pendek seperti "a" tidak mencocokkan hampir
+// seluruh indeks
+const maxPrefixTerms = 50
+
+// minISBNSearchLength adalah panjang minimum digit pencarian yang dianggap mencari ISBN, sepanjang
+// ISBN-10; angka yang lebih pendek tetap dicocokkan ke ISBN tapi tidak diutamakan
+const minISBNSearchLength = 10
+
+var (
+	_ BookStore = (*BookRepository)(nil)
+	_ BookStore = (*MemoryBookStore)(nil)
+)
+
+// maxLimit adalah batas atas limit per halaman supaya satu request tidak mengambil seluruh katalog
+const maxLimit = 100
+
+// normalizeLimit memakai 10 untuk limit yang tidak valid dan memotong limit di atas maxLimit
+func normalizeLimit(limit int) int {
+	if limit < 1 {
+		return 10
+	}
+	return min(limit, maxLimit)
+}
+
+// paginate menormalkan page dan limit lalu menghitung offset dan meta pagination
+func paginate(page, limit int, total int64) (*Pagination, int) {
+	if page < 1 {
+		page = 1
+	}
+	limit = normalizeLimit(limit)
+
+	return &Pagination{
+		Page:      page,
+		Limit:     limit,
+		Total:     total,
+		TotalPage: int(math.Ceil(float64(total) / float64(limit))),
+	}, (page - 1) * limit
+}
+
+// canonicalISBN mengubah ISBN ke bentuk yang disimpan supaya ISBN-10 dan ISBN-13 dari buku yang
+// sama dianggap sama. ISBN yang tidak valid hanya dibuang tanda hubung dan spasinya.
+func canonicalISBN(s string) string {
+	if canonical, ok := isbn.Normalize(s); ok {
+		return canonical
+	}
+	return isbn.Compact(s)
+}
+
+// bookDocument adalah field book yang diindeks untuk pencarian
+func bookDocument(book *models.Book) search.Document {
+	return search.Document{
+		ID: book.ID,
+		Fields: map[string]string{
+			search.FieldTitle:       book.Title,
+			search.FieldAuthor:      book.Author,
+			search.FieldPublisher:   book.Publisher,
+			search.FieldDescription: book.Description,
+		},
+	}
+}
+
+// bookSearch adalah teks pencarian yang sudah disiapkan store: query beserta koreksi salah ketiknya,
+// term indeks yang dicocokkan, dan potongan ISBN jika teks pencarian berupa angka
+type bookSearch struct {
+	query search.Query
+	// terms adalah term indeks yang memenuhi salah satu term query: kata utuh, paling banyak
+	// maxPrefixTerms awalan term terakhir, dan hasil koreksi
+	terms    []string
+	isbn     string
+	fullISBN bool
+}
+
+func newBookSearch(text string) *bookSearch {
+	s := &bookSearch{query: search.ParseQuery(text)}
+	s.isbn, s.fullISBN, _ = isbnQuery(text)
+	return s
+}
+
+// complete menyimpan query yang sudah dikoreksi beserta term indeks yang ditemukan untuknya
+func (s *bookSearch) complete(q search.Query, indexed []string) {
+	s.query = q
+	terms := slices.Clone(indexed)
+	slices.Sort(terms)
+	s.terms = slices.Compact(terms)
+}
+
+// termGroups mengelompokkan terms per term query. Nil jika ada term query yang tidak dipenuhi term
+// indeks mana pun, artinya tidak ada book yang cocok lewat teks.
+func (s *bookSearch) termGroups() [][]string {
+	if s.query.Empty() {
+		return nil
+	}
+	groups := make([][]string, len(s.query.Terms))
+	for _, term := range s.terms {
+		if i := s.query.Index(term); i >= 0 {
+			groups[i] = append(groups[i], term)
+		}
+	}
+	for _, group := range groups {
+		if len(group) == 0 {
+			return nil
+		}
+	}
+	return groups
+}
+
+// isbnQuery mengembalikan potongan ISBN yang dicari jika teks pencarian hanya berisi digit ISBN.
+// full true jika potongannya cukup panjang untuk dianggap pencarian ISBN, bukan angka biasa seperti
+// tahun di judul "1984".
+func isbnQuery(s string) (digits string, full, ok bool) {
+	compact := canonicalISBN(s)
+	if compact == "" {
+		return "", false, false
+	}
+	for _, r := range compact {
+		if (r < '0' || r > '9') && r != 'X' {
+			return "", false, false
+		}
+	}
+	return compact, len(compact) >= minISBNSearchLength, true
+}
+
+// rankBooks mengurutkan hasil pencarian teks beserta book yang ISBN-nya cocok. Untuk pencarian
+// ISBN (fullISBN) book itu selalu di atas karena hampir pasti yang dicari; untuk angka pendek
+// kecocokan ISBN dinilai seperti satu kata yang cocok di field berbobot 1.
+func rankBooks(q search.Query, postings []search.Posting, totalDocs int, isbnIDs []uint, fullISBN bool) []search.Hit {
+	hits := search.Rank(q, postings, totalDocs)
+	if len(isbnIDs) == 0 {
+		return hits
+	}
+
+	if !fullISBN {
+		scores := map[uint]float64{}
+		for _, hit := range hits {
+			scores[hit.DocID] = hit.Score
+		}
+		isbnScore := search.IDF(len(isbnIDs), totalDocs)
+		for _, id := range isbnIDs {
+			scores[id] += isbnScore
+		}
+
+		ranked := make([]search.Hit, 0, len(scores))
+		for id, score := range scores {
+			ranked = append(ranked, search.Hit{DocID: id, Score: score})
+		}
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Score != ranked[j].Score {
+				return ranked[i].Score > ranked[j].Score
+			}
+			return ranked[i].DocID > ranked[j].DocID
+		})
+		return ranked
+	}
+
+	isbnScore := 1.0
+	if len(hits) > 0 {
+		isbnScore += hits[0].Score
+	}
+	sort.Slice(isbnIDs, func(i, j int) bool { return isbnIDs[i] > isbnIDs[j] })
+
+	ranked := make([]search.Hit, 0, len(isbnIDs)+len(hits))
+	seen := map[uint]bool{}
+	for _, id := range isbnIDs {
+		ranked = append(ranked, search.Hit{DocID: id, Score: isbnScore})
+		seen[id] = true
+	}
+	for _, hit := range hits {
+		if !seen[hit.DocID] {
+			ranked = append(ranked, hit)
+		}
+	}
+	return ranked
+}
+
+// suggestions mengambil judul dan penulis yang semua kata query-nya cocok dari book di hits, urut
+// dari book paling relevan. Penulis yang sama digabung dan dihitung jumlah book-nya.
+func suggestions(q search.Query, hits []search.Hit, books map[uint]models.Book, limit int) []Suggestion {
+	limit = min(max(limit, 1), MaxSuggestions)
+
+	result := []Suggestion{}
+	authors := map[string]int{}
+	for _, hit := range hits {
+		book, ok := books[hit.DocID]
+		if !ok {
+			continue
+		}
+
+		if q.Matches(book.Author) {
+			key := strings.ToLower(book.Author)
+			if i, seen := authors[key]; seen {
+				result[i].BookCount++
+			} else {
+				authors[key] = len(result)
+				result = append(result, newSuggestion(q, search.FieldAuthor, book.Author))
+				result[len(result)-1].BookCount = 1
+			}
+		}
+		if q.Matches(book.Title) {
+			suggestion := newSuggestion(q, search.FieldTitle, book.Title)
+			suggestion.BookID = book.ID
+			result = append(result, suggestion)
+		}
+	}
+	return pageOf(result, 0, limit)
+}
+
+func newSuggestion(q search.Query, field, text string) Suggestion {
+	highlight, _ := search.Highlight(text, q, snippetLength)
+	return Suggestion{Text: text, Field: field, Highlight: highlight}
+}
+
+func highlightBook(q search.Query, book *models.Book) map[string]string {
+	highlights := map[string]string{}
+	for field, text := range bookDocument(book).Fields {
+		if snippet, ok := search.Highlight(text, q, snippetLength); ok {
+			highlights[field] = snippet
+		}
+	}
+	return highlights
+}
+
+// bookHits menyusun hasil pencarian untuk ids sesuai urutannya beserta skor dan highlight-nya.
+// id yang book-nya tidak ada di books dilewati.
+func bookHits(q search.Query, ids []uint, books map[uint]models.Book, hits []search.Hit) []BookHit {
+	scores := make(map[uint]float64, len(hits))
+	for _, hit := range hits {
+		scores[hit.DocID] = hit.Score
+	}
+
+	results := make([]BookHit, 0, len(ids))
+	for _, id := range ids {
+		book, ok := books[id]
+		if !ok {
+			continue
+		}
+		results = append(results, BookHit{
+			Book:       book,
+			Score:      math.Round(scores[id]*1e4) / 1e4,
+			Highlights: highlightBook(q, &book),
+		})
+	}
+	return results
+}
+
+func hitIDs(hits []search.Hit) []uint {
+	ids := make([]uint, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.DocID)
+	}
+	return ids
+}
+
+// facetValue mengembalikan nilai facet name milik book, kosong jika book tidak punya nilai
+func facetValue(book *models.Book, name string) string {
+	switch name {
+	case FacetCategory:
+		return book.Category
+	case FacetStatus:
+		return book.Status
+	case FacetAuthor:
+		return book.Author
+	case FacetPublisher:
+		return book.Publisher
+	case FacetDecade:
+		if year := book.PublisherAt.Year(); year >= 1000 {
+			return strconv.Itoa(year/10*10) + "s"
+		}
+	}
+	return ""
+}
+
+// countFacets menghitung facet dari daftar book di memori dengan urutan dan batas yang sama
+// dengan BookRepository.Facets
+func countFacets(books []models.Book, names []string) map[string][]FacetCount {
+	facets := make(map[string][]FacetCount, len(names))
+	for _, name := range names {
+		totals := map[string]int64{}
+		for i := range books {
+			if value := facetValue(&books[i], name); value != "" {
+				totals[value]++
+			}
+		}
+
+		counts := make([]FacetCount, 0, len(totals))
+		for value, count := range totals {
+			counts = append(counts, FacetCount{Value: value, Count: count})
+		}
+		sort.Slice(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Value < counts[j].Value
+		})
+		if len(counts) > facetLimit {
+			counts = counts[:facetLimit]
+		}
+		facets[name] = counts
+	}
+	return facets
+}
@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// notFound menerjemahkan gorm.ErrRecordNotFound menjadi error domain notFoundErr. Error asli tetap
+// ada di rantai Unwrap, error lain dikembalikan apa adanya.
+func notFound(err error, notFoundErr *apperror.Error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return notFoundErr.Wrap(err)
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrFineNotFound       = apperror.NotFound("fine_not_found", "Denda tidak ada")
+	ErrFineNotOutstanding = apperror.Conflict("fine_not_outstanding", "Denda sudah lunas")
+	ErrAmountExceedsFine  = apperror.Validation("amount_exceeds_fine", "amount melebihi sisa denda")
+)
+
+type FineRepository struct {
+	DB *gorm.DB
+}
+
+func NewFineRepository(db *gorm.DB) *FineRepository {
+	return &FineRepository{
+		DB: db,
+	}
+}
+
+type FineFilter struct {
+	UserID uint
+	Status string
+	Page   int
+	Limit  int
+}
+
+// GetAll fines dengan pagination dan filter
+func (r *FineRepository) GetAll(filter FineFilter) ([]models.Fine, *Pagination, error) {
+	var fines []models.Fine
+	var total int64
+
+	query := r.DB.Model(&models.Fine{})
+
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	totalPage := int(math.Ceil(float64(total) / float64(filter.Limit)))
+
+	pagination := &Pagination{
+		Page:      filter.Page,
+		Limit:     filter.Limit,
+		Total:     total,
+		TotalPage: totalPage,
+	}
+
+	err := query.Offset(offset).Limit(filter.Limit).Order("created_at DESC").Find(&fines).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fines, pagination, nil
+}
+
+// GetByID mencari fine by ID
+func (r *FineRepository) GetByID(id uint) (*models.Fine, error) {
+	var fine models.Fine
+	err := r.DB.First(&fine, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrFineNotFound)
+	}
+	return &fine, nil
+}
+
+// GetTransactions mengambil ledger pembayaran dan penghapusan sebuah fine
+func (r *FineRepository) GetTransactions(fineID uint) ([]models.FineTransaction, error) {
+	var transactions []models.FineTransaction
+	err := r.DB.Where("fine_id = ?", fineID).Order("id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// Balance menghitung total denda member: sisa fine tercatat ditambah denda berjalan dari pinjaman
+// aktif yang sudah lewat jatuh tempo
+func (r *FineRepository) Balance(userID uint, now time.Time, policy models.FinePolicy) (*models.FineBalance, error) {
+	balance := &models.FineBalance{UserID: userID}
+
+	err := r.DB.Model(&models.Fine{}).
+		Select("COALESCE(SUM(amount - paid_amount - waived_amount), 0)").
+		Where("user_id = ? AND status = ?", userID, models.FineStatusOutstanding).
+		Scan(&balance.Outstanding).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var overdueLoans []models.Loan
+	err = r.DB.Where("user_id = ? AND returned_at IS NULL AND due_at < ?", userID, now).
+		Find(&overdueLoans).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range overdueLoans {
+		balance.Accruing += policy.Calculate(overdueLoans[i].DaysOverdue(now))
+	}
+
+	balance.Total = balance.Outstanding + balance.Accruing
+	return balance, nil
+}
+
+// RecordPayment mencatat pembayaran denda ke ledger
+func (r *FineRepository) RecordPayment(fineID uint, amount int64, note string, recordedByID uint) (*models.Fine, error) {
+	return r.applyTransaction(fineID, models.FineTransactionPayment, amount, note, recordedByID)
+}
+
+// Waive menghapus sebagian atau seluruh sisa denda. Amount nol berarti seluruh sisa denda.
+func (r *FineRepository) Waive(fineID uint, amount int64, note string, recordedByID uint) (*models.Fine, error) {
+	return r.applyTransaction(fineID, models.FineTransactionWaiver, amount, note, recordedByID)
+}
+
+func (r *FineRepository) applyTransaction(fineID uint, txType string, amount int64, note string, recordedByID uint) (*models.Fine, error) {
+	var fine models.Fine
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&fine, fineID).Error; err != nil {
+			return notFound(err, ErrFineNotFound)
+		}
+
+		outstanding := fine.Outstanding()
+		if fine.Status != models.FineStatusOutstanding || outstanding <= 0 {
+			return ErrFineNotOutstanding
+		}
+		if amount == 0 && txType == models.FineTransactionWaiver {
+			amount = outstanding
+		}
+		if amount > outstanding {
+			return ErrAmountExceedsFine
+		}
+
+		transaction := models.FineTransaction{
+			FineID:       fine.ID,
+			Type:         txType,
+			Amount:       amount,
+			Note:         note,
+			RecordedByID: recordedByID,
+		}
+		if err := tx.Create(&transaction).Error; err != nil {
+			return err
+		}
+
+		if txType == models.FineTransactionPayment {
+			fine.PaidAmount += amount
+		} else {
+			fine.WaivedAmount += amount
+		}
+
+		if fine.Outstanding() == 0 {
+			// Lunas dianggap paid jika ada pembayaran, waived jika seluruhnya dihapus
+			fine.Status = models.FineStatusPaid
+			if fine.PaidAmount == 0 {
+				fine.Status = models.FineStatusWaived
+			}
+		}
+
+		return tx.Model(&fine).Select("paid_amount", "waived_amount", "status").Updates(&fine).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &fine, nil
+}
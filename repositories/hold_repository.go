@@ -0,0 +1,261 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrHoldNotFound         = apperror.NotFound("hold_not_found", "Hold tidak ada")
+	ErrBookHasAvailableCopy = apperror.Conflict("book_available", "Buku masih tersedia, silakan langsung dipinjam")
+	ErrHoldExists           = apperror.Conflict("hold_exists", "User sudah ada di antrean hold buku ini")
+	ErrAlreadyBorrowing     = apperror.Conflict("already_borrowing", "User sedang meminjam buku ini")
+	ErrHoldNotActive        = apperror.Conflict("hold_not_active", "Hold sudah tidak aktif")
+	ErrHeldForOthers        = apperror.Conflict("held_for_others", "Buku sedang direservasi untuk member lain di antrean hold")
+)
+
+type HoldRepository struct {
+	DB *gorm.DB
+}
+
+func NewHoldRepository(db *gorm.DB) *HoldRepository {
+	return &HoldRepository{
+		DB: db,
+	}
+}
+
+// GetQueue mengambil hold aktif sebuah book urut FIFO (yang pertama masuk di depan)
+func (r *HoldRepository) GetQueue(bookID uint) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.DB.Where("book_id = ? AND status IN ?", bookID, activeHoldStatuses).
+		Order("id ASC").
+		Find(&holds).Error
+	return holds, err
+}
+
+// GetByID mencari hold by ID
+func (r *HoldRepository) GetByID(id uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.DB.First(&hold, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrHoldNotFound)
+	}
+	return &hold, nil
+}
+
+// Place memasukkan user ke antrean hold book. Hanya boleh jika tidak ada eksemplar tersedia,
+// user belum punya hold aktif dan tidak sedang meminjam book yang sama.
+func (r *HoldRepository) Place(userID, bookID uint) (*models.Hold, error) {
+	var hold models.Hold
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		// Kunci baris book supaya antrean tidak balapan dengan checkout/return
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Book{}, bookID).Error; err != nil {
+			return notFound(err, ErrBookNotFound)
+		}
+
+		var count int64
+		err := tx.Model(&models.Hold{}).
+			Where("book_id = ? AND user_id = ? AND status IN ?", bookID, userID, activeHoldStatuses).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrHoldExists
+		}
+
+		err = tx.Model(&models.Loan{}).
+			Where("book_id = ? AND user_id = ? AND returned_at IS NULL", bookID, userID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAlreadyBorrowing
+		}
+
+		err = tx.Model(&models.BookCopy{}).
+			Where("book_id = ? AND status = ?", bookID, models.CopyStatusAvailable).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBookHasAvailableCopy
+		}
+
+		hold = models.Hold{
+			BookID: bookID,
+			UserID: userID,
+			Status: models.HoldStatusWaiting,
+		}
+		return tx.Create(&hold).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// Cancel membatalkan hold aktif. Eksemplar yang sudah disisihkan diteruskan ke antrean berikutnya.
+func (r *HoldRepository) Cancel(id uint, now time.Time) (*models.Hold, error) {
+	var hold models.Hold
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, id).Error; err != nil {
+			return notFound(err, ErrHoldNotFound)
+		}
+
+		if !hold.IsActive() {
+			return ErrHoldNotActive
+		}
+
+		return closeHold(tx, &hold, models.HoldStatusCancelled, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &hold, nil
+}
+
+// ExpireOverdue menutup semua hold ready yang tidak diambil sampai ExpiresAt
+func (r *HoldRepository) ExpireOverdue(now time.Time) (int, error) {
+	var bookIDs []uint
+	err := r.DB.Model(&models.Hold{}).
+		Where("status = ? AND expires_at < ?", models.HoldStatusReady, now).
+		Distinct().
+		Pluck("book_id", &bookIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, bookID := range bookIDs {
+		err := r.DB.Transaction(func(tx *gorm.DB) error {
+			n, err := expireOverdueHolds(tx, bookID, now)
+			expired += n
+			return err
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+var activeHoldStatuses = []string{models.HoldStatusWaiting, models.HoldStatusReady}
+
+// expireOverdueHolds mengubah hold ready yang lewat ExpiresAt milik satu book menjadi expired
+func expireOverdueHolds(tx *gorm.DB, bookID uint, now time.Time) (int, error) {
+	var holds []models.Hold
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("book_id = ? AND status = ? AND expires_at < ?", bookID, models.HoldStatusReady, now).
+		Order("id ASC").
+		Find(&holds).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range holds {
+		if err := closeHold(tx, &holds[i], models.HoldStatusExpired, now); err != nil {
+			return i, err
+		}
+	}
+	return len(holds), nil
+}
+
+// closeHold menutup hold dengan status akhir. Jika hold memegang eksemplar, eksemplar dilepas
+// dan langsung ditawarkan ke antrean berikutnya.
+func closeHold(tx *gorm.DB, hold *models.Hold, status string, now time.Time) error {
+	heldCopyID := hold.CopyID
+	if status != models.HoldStatusFulfilled {
+		hold.CopyID = nil
+	}
+	hold.Status = status
+	hold.ClosedAt = &now
+
+	err := tx.Model(hold).Select("status", "copy_id", "closed_at").Updates(hold).Error
+	if err != nil {
+		return err
+	}
+
+	if heldCopyID == nil || status == models.HoldStatusFulfilled {
+		return nil
+	}
+
+	err = tx.Model(&models.BookCopy{}).
+		Where("id = ? AND status = ?", *heldCopyID, models.CopyStatusOnHold).
+		Update("status", models.CopyStatusAvailable).Error
+	if err != nil {
+		return err
+	}
+	return refreshBook(tx, hold.BookID)
+}
+
+// promoteHolds menyisihkan eksemplar tersedia untuk hold waiting paling depan, berulang sampai
+// eksemplar atau antrean habis
+func promoteHolds(tx *gorm.DB, bookID uint, now time.Time) error {
+	for {
+		var hold models.Hold
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("book_id = ? AND status = ?", bookID, models.HoldStatusWaiting).
+			Order("id ASC").
+			First(&hold).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var bookCopy models.BookCopy
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("book_id = ? AND status = ?", bookID, models.CopyStatusAvailable).
+			Order("id ASC").
+			First(&bookCopy).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&bookCopy).Update("status", models.CopyStatusOnHold).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(holdPickupWindow())
+		hold.Status = models.HoldStatusReady
+		hold.CopyID = &bookCopy.ID
+		hold.ReadyAt = &now
+		hold.ExpiresAt = &expiresAt
+		err = tx.Model(&hold).Select("status", "copy_id", "ready_at", "expires_at").Updates(&hold).Error
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// refreshBook meneruskan eksemplar tersedia ke antrean hold lalu menghitung ulang status book
+func refreshBook(tx *gorm.DB, bookID uint) error {
+	if err := promoteHolds(tx, bookID, time.Now()); err != nil {
+		return err
+	}
+	return syncBookStatus(tx, bookID)
+}
+
+func holdPickupWindow() time.Duration {
+	if config.Library == nil {
+		return 3 * 24 * time.Hour
+	}
+	return config.Library.HoldPickupWindow
+}
@@ -0,0 +1,350 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrLoanNotFound        = apperror.NotFound("loan_not_found", "Loan tidak ada")
+	ErrBookNotAvailable    = apperror.Conflict("book_not_available", "Buku sedang tidak tersedia untuk dipinjam")
+	ErrLoanAlreadyReturned = apperror.Conflict("loan_already_returned", "Loan sudah dikembalikan")
+	ErrRenewalLimitReached = apperror.Conflict("renewal_limit_reached", "Batas perpanjangan sudah tercapai")
+	ErrHoldPending         = apperror.Conflict("hold_pending", "Buku sedang di-hold member lain, tidak bisa diperpanjang")
+	ErrLoanOverdue         = apperror.Conflict("loan_overdue", "Loan sudah lewat jatuh tempo, kembalikan buku dan selesaikan dendanya")
+)
+
+type LoanRepository struct {
+	DB *gorm.DB
+}
+
+func NewLoanRepository(db *gorm.DB) *LoanRepository {
+	return &LoanRepository{
+		DB: db,
+	}
+}
+
+type LoanFilter struct {
+	UserID uint
+	BookID uint
+	Active bool
+	Page   int
+	Limit  int
+}
+
+// GetAll loans dengan pagination dan filter
+func (r *LoanRepository) GetAll(filter LoanFilter) ([]models.Loan, *Pagination, error) {
+	var loans []models.Loan
+	var total int64
+
+	query := r.DB.Model(&models.Loan{})
+
+	if filter.UserID > 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.BookID > 0 {
+		query = query.Where("book_id = ?", filter.BookID)
+	}
+
+	if filter.Active {
+		query = query.Where("returned_at IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	totalPage := int(math.Ceil(float64(total) / float64(filter.Limit)))
+
+	pagination := &Pagination{
+		Page:      filter.Page,
+		Limit:     filter.Limit,
+		Total:     total,
+		TotalPage: totalPage,
+	}
+
+	err := query.Preload("Book", unscoped).Preload("Copy").Offset(offset).Limit(filter.Limit).Order("borrowed_at DESC").Find(&loans).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return loans, pagination, nil
+}
+
+// unscoped dipakai saat preload Book supaya riwayat loan tetap menampilkan buku yang sudah di-trash
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
+// GetByID mencari loan by ID beserta bukunya
+func (r *LoanRepository) GetByID(id uint) (*models.Loan, error) {
+	var loan models.Loan
+	err := r.DB.Preload("Book", unscoped).Preload("Copy").First(&loan, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrLoanNotFound)
+	}
+	return &loan, nil
+}
+
+// GetRenewals mengambil riwayat perpanjangan loan
+func (r *LoanRepository) GetRenewals(loanID uint) ([]models.LoanRenewal, error) {
+	var renewals []models.LoanRenewal
+	err := r.DB.Where("loan_id = ?", loanID).Order("id ASC").Find(&renewals).Error
+	return renewals, err
+}
+
+// Renew memperpanjang due_at sebesar loanPeriod dalam satu transaksi. Ditolak jika loan sudah
+// kembali, sudah lewat jatuh tempo pada renewedAt (perpanjangan akan menghapus denda yang dicatat
+// Return), sudah mencapai maxRenewals, atau ada member lain di antrean hold book tersebut.
+func (r *LoanRepository) Renew(id uint, renewedAt time.Time, loanPeriod time.Duration, maxRenewals int, renewedByID uint) (*models.Loan, error) {
+	var loan models.Loan
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&loan, id).Error; err != nil {
+			return notFound(err, ErrLoanNotFound)
+		}
+
+		if loan.ReturnedAt != nil {
+			return ErrLoanAlreadyReturned
+		}
+		if loan.IsOverdue(renewedAt) {
+			return ErrLoanOverdue
+		}
+		if loan.RenewalCount >= maxRenewals {
+			return ErrRenewalLimitReached
+		}
+
+		var holds int64
+		err := tx.Model(&models.Hold{}).
+			Where("book_id = ? AND user_id != ? AND status IN ?", loan.BookID, loan.UserID, activeHoldStatuses).
+			Count(&holds).Error
+		if err != nil {
+			return err
+		}
+		if holds > 0 {
+			return ErrHoldPending
+		}
+
+		renewal := models.LoanRenewal{
+			LoanID:        loan.ID,
+			PreviousDueAt: loan.DueAt,
+			NewDueAt:      loan.DueAt.Add(loanPeriod),
+			RenewedByID:   renewedByID,
+		}
+		if err := tx.Create(&renewal).Error; err != nil {
+			return err
+		}
+
+		loan.DueAt = renewal.NewDueAt
+		loan.RenewalCount++
+		err = tx.Model(&loan).Select("due_at", "renewal_count").Updates(&loan).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Preload("Book", unscoped).Preload("Copy").First(&loan, loan.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+// HasActiveLoan mengecek apakah book sedang dipinjam
+func (r *LoanRepository) HasActiveLoan(bookID uint) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.Loan{}).
+		Where("book_id = ? AND returned_at IS NULL", bookID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Checkout meminjamkan eksemplar ke user dalam satu transaksi. Jika copyID nol, eksemplar yang
+// disisihkan untuk hold user dipakai, atau eksemplar tersedia pertama dari bookID. Selama antrean
+// hold book tidak kosong hanya kepala antrean yang boleh checkout.
+func (r *LoanRepository) Checkout(userID, bookID, copyID uint, borrowedAt time.Time, loanPeriod time.Duration) (*models.Loan, error) {
+	var loan models.Loan
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+
+		var bookCopy models.BookCopy
+		if copyID > 0 {
+			if err := locked.First(&bookCopy, copyID).Error; err != nil {
+				return notFound(err, ErrCopyNotFound)
+			}
+			if bookID > 0 && bookCopy.BookID != bookID {
+				return ErrCopyNotFound
+			}
+			bookID = bookCopy.BookID
+		}
+
+		if err := locked.First(&models.Book{}, bookID).Error; err != nil {
+			return notFound(err, ErrBookNotFound)
+		}
+
+		if _, err := expireOverdueHolds(tx, bookID, borrowedAt); err != nil {
+			return err
+		}
+
+		var head *models.Hold
+		var queue []models.Hold
+		err := locked.Where("book_id = ? AND status IN ?", bookID, activeHoldStatuses).
+			Order("id ASC").Find(&queue).Error
+		if err != nil {
+			return err
+		}
+		for i := range queue {
+			if queue[i].UserID == userID {
+				head = &queue[i]
+				break
+			}
+			// Hold orang lain di depan user, kecuali hold ready yang sudah memegang eksemplar sendiri
+			if queue[i].Status == models.HoldStatusWaiting {
+				return ErrHeldForOthers
+			}
+		}
+
+		switch {
+		case copyID > 0:
+			// Ambil ulang setelah hold kedaluwarsa mungkin melepas eksemplar ini
+			if err := tx.First(&bookCopy, copyID).Error; err != nil {
+				return err
+			}
+			if bookCopy.Status == models.CopyStatusOnHold && (head == nil || head.CopyID == nil || *head.CopyID != bookCopy.ID) {
+				return ErrHeldForOthers
+			}
+			if bookCopy.Status != models.CopyStatusAvailable && bookCopy.Status != models.CopyStatusOnHold {
+				return ErrBookNotAvailable
+			}
+		case head != nil && head.CopyID != nil:
+			if err := locked.First(&bookCopy, *head.CopyID).Error; err != nil {
+				return err
+			}
+		default:
+			err := locked.Where("book_id = ? AND status = ?", bookID, models.CopyStatusAvailable).
+				Order("id ASC").First(&bookCopy).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBookNotAvailable
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		loan = models.Loan{
+			UserID:     userID,
+			BookID:     bookID,
+			CopyID:     &bookCopy.ID,
+			BorrowedAt: borrowedAt,
+			DueAt:      borrowedAt.Add(loanPeriod),
+		}
+		if err := tx.Create(&loan).Error; err != nil {
+			return err
+		}
+
+		bookCopy.Status = models.CopyStatusBorrowed
+		if err := tx.Model(&bookCopy).Update("status", bookCopy.Status).Error; err != nil {
+			return err
+		}
+
+		// Hold user terpenuhi; eksemplar yang disisihkan tapi tidak jadi dipakai dilepas ke antrean
+		if head != nil {
+			heldCopyID := head.CopyID
+			if err := closeHold(tx, head, models.HoldStatusFulfilled, borrowedAt); err != nil {
+				return err
+			}
+			if heldCopyID != nil && *heldCopyID != bookCopy.ID {
+				err := tx.Model(&models.BookCopy{}).Where("id = ?", *heldCopyID).
+					Update("status", models.CopyStatusAvailable).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := refreshBook(tx, bookID); err != nil {
+			return err
+		}
+
+		loan.Copy = &bookCopy
+		return tx.Unscoped().First(&loan.Book, loan.BookID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &loan, nil
+}
+
+// Return menandai loan sudah dikembalikan dalam satu transaksi. Eksemplar kembali available lalu
+// langsung disisihkan untuk hold berikutnya jika ada antrean, dan status book dihitung ulang.
+// Jika terlambat, Fine dicatat sesuai policy dan ikut dikembalikan.
+func (r *LoanRepository) Return(id uint, returnedAt time.Time, policy models.FinePolicy) (*models.Loan, *models.Fine, error) {
+	var loan models.Loan
+	var fine *models.Fine
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&loan, id).Error; err != nil {
+			return notFound(err, ErrLoanNotFound)
+		}
+
+		if loan.ReturnedAt != nil {
+			return ErrLoanAlreadyReturned
+		}
+
+		loan.ReturnedAt = &returnedAt
+		if err := tx.Model(&loan).Update("returned_at", loan.ReturnedAt).Error; err != nil {
+			return err
+		}
+
+		if days := loan.DaysOverdue(returnedAt); days > 0 {
+			fine = &models.Fine{
+				UserID:      loan.UserID,
+				LoanID:      loan.ID,
+				DaysOverdue: days,
+				Amount:      policy.Calculate(days),
+				Status:      models.FineStatusOutstanding,
+			}
+			if fine.Amount == 0 {
+				fine.Status = models.FineStatusPaid
+			}
+			if err := tx.Create(fine).Error; err != nil {
+				return err
+			}
+		}
+
+		if loan.CopyID != nil {
+			if err := tx.Model(&models.BookCopy{}).Where("id = ?", *loan.CopyID).
+				Update("status", models.CopyStatusAvailable).Error; err != nil {
+				return err
+			}
+		}
+		if err := refreshBook(tx, loan.BookID); err != nil {
+			return err
+		}
+
+		return tx.Preload("Book", unscoped).Preload("Copy").First(&loan, loan.ID).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &loan, fine, nil
+}
@@ -0,0 +1,541 @@
+package repositories
+
+import (
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/search"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryBookStore adalah BookStore di memori tanpa database, untuk test dan demo mode.
+// Eksemplar hanya dicatat statusnya; loan dan hold tidak ada, jadi tidak ada eksemplar dipinjam.
+type MemoryBookStore struct {
+	mu     sync.RWMutex
+	books  map[uint]models.Book
+	copies map[uint][]models.BookCopy
+	nextID uint
+	now    func() time.Time
+}
+
+// NewMemoryBookStore membuat store berisi books awal, masing-masing dengan eksemplar di book.Copies
+// atau satu eksemplar tersedia jika kosong
+func NewMemoryBookStore(books ...models.Book) *MemoryBookStore {
+	store := &MemoryBookStore{
+		books:  map[uint]models.Book{},
+		copies: map[uint][]models.BookCopy{},
+		now:    time.Now,
+	}
+
+	for _, book := range books {
+		if len(book.Copies) == 0 {
+			book.Copies = []models.BookCopy{{Status: models.CopyStatusAvailable}}
+		}
+		if err := store.Create(&book); err != nil {
+			panic(err)
+		}
+	}
+	return store
+}
+
+func (s *MemoryBookStore) GetAll(filter BookFilter) ([]models.Book, *Pagination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []models.Book
+	for _, book := range s.books {
+		if !book.DeletedAt.Valid && matchesFilter(&book, filter) {
+			matched = append(matched, book)
+		}
+	}
+
+	order := bookSort(filter)
+	slices.SortFunc(matched, func(a, b models.Book) int {
+		return compareBooks(&a, &b, order)
+	})
+
+	pagination, offset := paginate(filter.Page, filter.Limit, int64(len(matched)))
+	return pageOf(matched, offset, pagination.Limit), pagination, nil
+}
+
+func (s *MemoryBookStore) GetAllByCursor(filter BookFilter, cursor string) ([]models.Book, *CursorPagination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	order := bookSort(filter)
+	var boundary *models.Book
+	prev := false
+	if cursor != "" {
+		var err error
+		if boundary, prev, err = decodeCursor(cursor, order); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Untuk halaman sebelum cursor, book diurutkan terbalik seperti di BookRepository
+	compare := func(a, b *models.Book) int {
+		if prev {
+			return -compareBooks(a, b, order)
+		}
+		return compareBooks(a, b, order)
+	}
+
+	var matched []models.Book
+	for _, book := range s.books {
+		if book.DeletedAt.Valid || !matchesFilter(&book, filter) {
+			continue
+		}
+		if boundary != nil && compare(&book, boundary) <= 0 {
+			continue
+		}
+		matched = append(matched, book)
+	}
+	slices.SortFunc(matched, func(a, b models.Book) int {
+		return compare(&a, &b)
+	})
+
+	limit := normalizeLimit(filter.Limit)
+	books, page := cursorPage(pageOf(matched, 0, limit+1), order, limit, cursor != "", prev)
+	return books, page, nil
+}
+
+// Search membuat posting dari book yang tidak di trash setiap kali dipanggil, cukup untuk katalog
+// kecil di test dan demo mode
+func (s *MemoryBookStore) Search(filter BookFilter) (*SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits, query := s.searchHits(filter.Search)
+	return searchResults(query, hits, s.activeBooks(filter), filter), nil
+}
+
+// searchResults menyusun satu halaman hasil pencarian dari hits yang sudah diurutkan. books berisi
+// book yang lolos filter, hit yang book-nya tidak ada di books dilewati.
+// Jika filter.Sort diisi, hasil diurutkan menurut kolom tersebut, bukan relevansi.
+func searchResults(q search.Query, hits []search.Hit, books map[uint]models.Book, filter BookFilter) *SearchResult {
+	var ids []uint
+	for _, hit := range hits {
+		if _, ok := books[hit.DocID]; ok {
+			ids = append(ids, hit.DocID)
+		}
+	}
+	if len(filter.Sort) > 0 {
+		slices.SortFunc(ids, func(a, b uint) int {
+			first, second := books[a], books[b]
+			return compareBooks(&first, &second, filter.Sort)
+		})
+	}
+
+	pagination, offset := paginate(filter.Page, filter.Limit, int64(len(ids)))
+	return &SearchResult{
+		Hits:       bookHits(q, pageOf(ids, offset, pagination.Limit), books, hits),
+		Pagination: pagination,
+		DidYouMean: q.DidYouMean(),
+	}
+}
+
+func (s *MemoryBookStore) Suggest(text string, limit int) ([]Suggestion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits, query := s.searchHits(text)
+	return suggestions(query, hits, s.activeBooks(BookFilter{}), limit), nil
+}
+
+// activeBooks adalah book yang tidak di trash dan lolos filter, dipetakan by ID
+func (s *MemoryBookStore) activeBooks(filter BookFilter) map[uint]models.Book {
+	books := map[uint]models.Book{}
+	for _, book := range s.books {
+		if !book.DeletedAt.Valid && matchesFilter(&book, filter) {
+			books[book.ID] = book
+		}
+	}
+	return books
+}
+
+func (s *MemoryBookStore) Facets(filter BookFilter, names []string) (map[string][]FacetCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched map[uint]bool
+	if filter.Search != "" {
+		matched = map[uint]bool{}
+		hits, _ := s.searchHits(filter.Search)
+		for _, id := range hitIDs(hits) {
+			matched[id] = true
+		}
+	}
+
+	var books []models.Book
+	for _, book := range s.books {
+		if book.DeletedAt.Valid || !matchesFilter(&book, filter) {
+			continue
+		}
+		if matched != nil && !matched[book.ID] {
+			continue
+		}
+		books = append(books, book)
+	}
+	return countFacets(books, names), nil
+}
+
+// searchHits meranking book yang tidak di trash terhadap text dengan term indeks dan koreksi salah
+// ketik yang sama dengan BookRepository; pemanggil memegang s.mu
+func (s *MemoryBookStore) searchHits(text string) ([]search.Hit, search.Query) {
+	bs := newBookSearch(text)
+
+	var postings []search.Posting
+	var isbnIDs []uint
+	counts := map[string]int{}
+	totalDocs := 0
+	for _, book := range s.books {
+		if book.DeletedAt.Valid {
+			continue
+		}
+		totalDocs++
+		for _, posting := range bookDocument(&book).Postings() {
+			postings = append(postings, posting)
+			counts[posting.Term]++
+		}
+		if bs.isbn != "" && strings.Contains(book.ISBN, bs.isbn) {
+			isbnIDs = append(isbnIDs, book.ID)
+		}
+	}
+
+	query := bs.query
+	var indexed []string
+	for _, term := range query.Terms {
+		if counts[term] > 0 {
+			indexed = append(indexed, term)
+		}
+	}
+	indexed = append(indexed, prefixTerms(query.Prefix(), counts)...)
+
+	missing := query.Missing(indexed)
+	if len(query.Correctable(missing)) > 0 && !(bs.fullISBN && len(isbnIDs) > 0) {
+		var vocabulary []string
+		for _, posting := range postings {
+			if slices.Contains(fuzzyFields, posting.Field) {
+				vocabulary = append(vocabulary, posting.Term)
+			}
+		}
+		query = query.Correct(missing, vocabulary)
+		indexed = append(indexed, query.Corrections()...)
+	}
+	bs.complete(query, indexed)
+
+	var matched []search.Posting
+	for _, posting := range postings {
+		if _, found := slices.BinarySearch(bs.terms, posting.Term); found {
+			matched = append(matched, posting)
+		}
+	}
+	return rankBooks(query, matched, totalDocs, isbnIDs, bs.fullISBN), query
+}
+
+// prefixTerms mengambil paling banyak maxPrefixTerms term berawalan prefix yang paling sering
+// muncul, sama dengan BookRepository
+func prefixTerms(prefix string, counts map[string]int) []string {
+	if prefix == "" {
+		return nil
+	}
+	var terms []string
+	for term := range counts {
+		if strings.HasPrefix(term, prefix) {
+			terms = append(terms, term)
+		}
+	}
+	slices.SortFunc(terms, func(a, b string) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return strings.Compare(a, b)
+	})
+	return pageOf(terms, 0, maxPrefixTerms)
+}
+
+// matchesFilter mengecek semua filter BookFilter selain Search, sama dengan applyBookFilters
+func matchesFilter(book *models.Book, filter BookFilter) bool {
+	switch {
+	case len(filter.Statuses) > 0 && !slices.Contains(filter.Statuses, book.Status),
+		len(filter.Categories) > 0 && !slices.Contains(filter.Categories, book.Category),
+		filter.Author != "" && !strings.EqualFold(book.Author, filter.Author),
+		filter.Publisher != "" && !strings.EqualFold(book.Publisher, filter.Publisher),
+		filter.MinPages > 0 && book.TotalPages < filter.MinPages,
+		filter.MaxPages > 0 && book.TotalPages > filter.MaxPages,
+		filter.PublishedFrom != nil && book.PublisherAt.Before(*filter.PublishedFrom),
+		filter.PublishedTo != nil && !book.PublisherAt.Before(filter.PublishedTo.AddDate(0, 0, 1)),
+		filter.CreatedSince != nil && book.CreatedAt.Before(*filter.CreatedSince),
+		filter.UpdatedSince != nil && book.UpdatedAt.Before(*filter.UpdatedSince):
+		return false
+	}
+	return true
+}
+
+func (s *MemoryBookStore) GetByID(id uint) (*models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	book, ok := s.books[id]
+	if !ok || book.DeletedAt.Valid {
+		return nil, ErrBookNotFound
+	}
+	return &book, nil
+}
+
+func (s *MemoryBookStore) GetByISBN(isbn string) (*models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	isbn = canonicalISBN(isbn)
+	for _, book := range s.books {
+		if book.ISBN == isbn && !book.DeletedAt.Valid {
+			return &book, nil
+		}
+	}
+	return nil, ErrBookNotFound
+}
+
+func (s *MemoryBookStore) Create(book *models.Book) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book.ISBN = canonicalISBN(book.ISBN)
+	for _, existing := range s.books {
+		if existing.ISBN == book.ISBN {
+			return ErrISBNExists
+		}
+	}
+
+	s.nextID++
+	now := s.now()
+	book.ID = s.nextID
+	book.CreatedAt = now
+	book.UpdatedAt = now
+	book.DeletedAt = gorm.DeletedAt{}
+	book.Version = 1
+
+	copies := make([]models.BookCopy, len(book.Copies))
+	for i, bookCopy := range book.Copies {
+		bookCopy.ID = uint(i + 1)
+		bookCopy.BookID = book.ID
+		if bookCopy.Barcode == "" {
+			bookCopy.Barcode = fmt.Sprintf("B%06d-%03d", book.ID, i+1)
+		}
+		if bookCopy.Status == "" {
+			bookCopy.Status = models.CopyStatusAvailable
+		}
+		bookCopy.CreatedAt = now
+		bookCopy.UpdatedAt = now
+		copies[i] = bookCopy
+	}
+	book.Copies = copies
+
+	s.copies[book.ID] = copies
+	book.Status = s.deriveStatus(book.ID)
+	s.store(*book)
+	return nil
+}
+
+func (s *MemoryBookStore) Update(book *models.Book, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.books[book.ID]
+	if !ok || existing.DeletedAt.Valid {
+		return ErrBookNotFound
+	}
+	if existing.Version != book.Version {
+		return ErrBookModified
+	}
+	book.ISBN = canonicalISBN(book.ISBN)
+	for _, other := range s.books {
+		if other.ID != book.ID && other.ISBN == book.ISBN {
+			return ErrISBNExists
+		}
+	}
+
+	now := s.now()
+	if status != "" {
+		s.updateCopyStatus(book.ID, status, now)
+		book.Status = s.deriveStatus(book.ID)
+	}
+	book.CreatedAt = existing.CreatedAt
+	book.DeletedAt = existing.DeletedAt
+	book.UpdatedAt = now
+	book.Version++
+	s.store(*book)
+	return nil
+}
+
+func (s *MemoryBookStore) Delete(id, version uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[id]
+	if !ok || book.DeletedAt.Valid {
+		return ErrBookNotFound
+	}
+	if book.Version != version {
+		return ErrBookModified
+	}
+	book.DeletedAt = gorm.DeletedAt{Time: s.now(), Valid: true}
+	s.store(book)
+	return nil
+}
+
+func (s *MemoryBookStore) UpdateStatus(book *models.Book, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.books[book.ID]
+	if !ok || existing.DeletedAt.Valid {
+		return ErrBookNotFound
+	}
+	if existing.Version != book.Version {
+		return ErrBookModified
+	}
+
+	now := s.now()
+	s.updateCopyStatus(book.ID, status, now)
+	existing.Status = s.deriveStatus(book.ID)
+	existing.UpdatedAt = now
+	existing.Version++
+	s.store(existing)
+	*book = existing
+	return nil
+}
+
+func (s *MemoryBookStore) CheckISBNExists(isbn string, excludeID uint) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	isbn = canonicalISBN(isbn)
+	for _, book := range s.books {
+		if book.ISBN == isbn && book.ID != excludeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryBookStore) CountCopies(bookIDs []uint) (map[uint]models.CopyCounts, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[uint]models.CopyCounts, len(bookIDs))
+	for _, id := range bookIDs {
+		copies, ok := s.copies[id]
+		if !ok {
+			continue
+		}
+		count := models.CopyCounts{Total: len(copies)}
+		for _, bookCopy := range copies {
+			if bookCopy.Status == models.CopyStatusAvailable {
+				count.Available++
+			}
+		}
+		counts[id] = count
+	}
+	return counts, nil
+}
+
+func (s *MemoryBookStore) GetDeleted(page, limit int) ([]models.Book, *Pagination, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deleted []models.Book
+	for _, book := range s.books {
+		if book.DeletedAt.Valid {
+			deleted = append(deleted, book)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.Time.After(deleted[j].DeletedAt.Time)
+	})
+
+	pagination, offset := paginate(page, limit, int64(len(deleted)))
+	return pageOf(deleted, offset, pagination.Limit), pagination, nil
+}
+
+func (s *MemoryBookStore) GetDeletedByID(id uint) (*models.Book, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	book, ok := s.books[id]
+	if !ok || !book.DeletedAt.Valid {
+		return nil, ErrBookNotInTrash
+	}
+	return &book, nil
+}
+
+func (s *MemoryBookStore) Restore(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	book, ok := s.books[id]
+	if !ok || !book.DeletedAt.Valid {
+		return ErrBookNotInTrash
+	}
+	book.DeletedAt = gorm.DeletedAt{}
+	book.Version++
+	s.store(book)
+	return nil
+}
+
+func (s *MemoryBookStore) Purge(deletedBefore time.Time) (purged int64, skipped int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, book := range s.books {
+		if book.DeletedAt.Valid && book.DeletedAt.Time.Before(deletedBefore) {
+			delete(s.books, id)
+			delete(s.copies, id)
+			purged++
+		}
+	}
+	return purged, 0, nil
+}
+
+// store menyimpan salinan book tanpa Copies supaya data di map tidak ikut berubah lewat pointer pemanggil
+func (s *MemoryBookStore) store(book models.Book) {
+	book.Copies = nil
+	s.books[book.ID] = book
+}
+
+// updateCopyStatus mengubah status semua eksemplar book kecuali yang hilang; eksemplar hilang
+// tetap hilang sampai statusnya diubah satu per satu
+func (s *MemoryBookStore) updateCopyStatus(bookID uint, status string, now time.Time) {
+	copies := s.copies[bookID]
+	for i := range copies {
+		if copies[i].Status == models.CopyStatusLost {
+			continue
+		}
+		copies[i].Status = status
+		copies[i].UpdatedAt = now
+	}
+}
+
+func (s *MemoryBookStore) deriveStatus(bookID uint) string {
+	statuses := make([]string, 0, len(s.copies[bookID]))
+	for _, bookCopy := range s.copies[bookID] {
+		statuses = append(statuses, bookCopy.Status)
+	}
+	return models.DeriveBookStatus(statuses)
+}
+
+func pageOf[T any](items []T, offset, limit int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
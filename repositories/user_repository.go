@@ -1 +1,154 @@
 package repositories
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/models"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUserNotFound = apperror.NotFound("user_not_found", "User tidak ada")
+	ErrEmailExists  = apperror.Conflict("email_exists", "User dengan email sudah ada")
+)
+
+type UserRepository struct {
+	DB *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{
+		DB: db,
+	}
+}
+
+type UserFilter struct {
+	Search   string
+	Role     string
+	IsActive *bool
+	Page     int
+	Limit    int
+}
+
+// GetAll users dengan pagination dan filter
+func (r *UserRepository) GetAll(filter UserFilter) ([]models.User, *Pagination, error) {
+	var users []models.User
+	var total int64
+
+	query := r.DB.Model(&models.User{})
+
+	// Apply filters
+	if filter.Search != "" {
+		search := "%" + filter.Search + "%"
+		query = query.Where("LOWER(name) LIKE LOWER(?) OR LOWER(email) LIKE LOWER(?)", search, search)
+	}
+
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+
+	// Count total records
+	if err := query.Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Calculate pagination
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	totalPage := int(math.Ceil(float64(total) / float64(filter.Limit)))
+
+	pagination := &Pagination{
+		Page:      filter.Page,
+		Limit:     filter.Limit,
+		Total:     total,
+		TotalPage: totalPage,
+	}
+
+	err := query.Offset(offset).Limit(filter.Limit).Order("created_at DESC").Find(&users).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return users, pagination, nil
+}
+
+// GetByID mencari user by ID
+func (r *UserRepository) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.DB.First(&user, id).Error
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+// GetByEmail mencari user by email
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, notFound(err, ErrUserNotFound)
+	}
+	return &user, nil
+}
+
+// Create membuat user baru, password di-hash oleh hook BeforeCreate
+func (r *UserRepository) Create(user *models.User) error {
+	err := r.DB.Create(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrEmailExists.Wrap(err)
+	}
+	return err
+}
+
+// Update mengupdate user
+func (r *UserRepository) Update(user *models.User) error {
+	return r.DB.Save(user).Error
+}
+
+// Delete menghapus user (soft delete lewat gorm.DeletedAt)
+func (r *UserRepository) Delete(id uint) error {
+	return r.DB.Delete(&models.User{}, id).Error
+}
+
+// UpdateRole mengubah role user
+func (r *UserRepository) UpdateRole(id uint, role models.UserRole) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// SetActive mengaktifkan atau menonaktifkan user
+func (r *UserRepository) SetActive(id uint, active bool) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("is_active", active).Error
+}
+
+// UpdateLastLogin mencatat waktu login terakhir user
+func (r *UserRepository) UpdateLastLogin(id uint, at time.Time) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", id).Update("last_login", at).Error
+}
+
+// CheckEmailExists mengecek apakah email sudah dipakai, termasuk user yang sudah dihapus karena unique
+// index email tetap berlaku untuk baris yang di-soft delete
+func (r *UserRepository) CheckEmailExists(email string, excludeID uint) (bool, error) {
+	var count int64
+	query := r.DB.Unscoped().Model(&models.User{}).Where("email = ?", email)
+
+	if excludeID > 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+
+	err := query.Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,193 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// fuzzyMinLength adalah panjang minimum term yang dikoreksi; kata pendek terlalu mudah mirip
+	// dengan kata lain
+	fuzzyMinLength = 4
+	// fuzzyWeight untuk term pengganti hasil koreksi salah ketik, lebih rendah dari awalan
+	fuzzyWeight = 0.3
+)
+
+// MaxEdits adalah jarak edit maksimum untuk term: 1 untuk term pendek, 2 mulai 8 karakter, 0 jika
+// term terlalu pendek untuk dikoreksi
+func MaxEdits(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < fuzzyMinLength:
+		return 0
+	case n < 8:
+		return 1
+	}
+	return 2
+}
+
+// StartLetters adalah huruf awal yang boleh dimiliki pengganti term: huruf pertama term, atau huruf
+// keduanya jika dua huruf pertama tertukar atau huruf pertama berlebih. Salah ketik di huruf pertama
+// sendiri tidak dikoreksi, sehingga store bisa menyaring kandidat dengan awalan.
+func StartLetters(term string) []string {
+	runes := []rune(term)
+	if len(runes) < 2 || runes[0] == runes[1] {
+		return []string{string(runes[:min(len(runes), 1)])}
+	}
+	return []string{string(runes[0]), string(runes[1])}
+}
+
+// Correctable mengembalikan indeks di missing yang term-nya cukup panjang untuk dikoreksi. Jika
+// kosong, vocabulary tidak perlu diambil.
+func (q Query) Correctable(missing []int) []int {
+	var correctable []int
+	for _, i := range missing {
+		if MaxEdits(q.Terms[i]) > 0 {
+			correctable = append(correctable, i)
+		}
+	}
+	return correctable
+}
+
+// Missing mengembalikan indeks term query yang tidak cocok dengan satu pun term di indexed, yaitu
+// term yang ada di indeks
+func (q Query) Missing(indexed []string) []int {
+	found := make([]bool, len(q.Terms))
+	for _, term := range indexed {
+		if i, _ := q.match(term); i >= 0 {
+			found[i] = true
+		}
+	}
+
+	var missing []int
+	for i, ok := range found {
+		if !ok {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// Correct mencarikan pengganti dari vocabulary untuk term query di missing, yaitu term berawalan
+// StartLetters dengan jarak edit (Damerau-Levenshtein) paling kecil dalam batas MaxEdits. Query
+// yang dikembalikan juga mencocokkan pengganti itu dengan bobot lebih rendah, sehingga "orwel"
+// menemukan "Orwell".
+func (q Query) Correct(missing []int, vocabulary []string) Query {
+	corrected := Query{Terms: q.Terms, fuzzy: map[string]int{}, corrections: map[int]string{}}
+	for term, i := range q.fuzzy {
+		corrected.fuzzy[term] = i
+	}
+	for i, best := range q.corrections {
+		corrected.corrections[i] = best
+	}
+
+	for _, i := range q.Correctable(missing) {
+		term := q.Terms[i]
+		limit := MaxEdits(term)
+		starts := StartLetters(term)
+
+		var candidates []string
+		for _, word := range vocabulary {
+			if !slices.ContainsFunc(starts, func(start string) bool { return strings.HasPrefix(word, start) }) {
+				continue
+			}
+			distance := editDistance(term, word, limit)
+			switch {
+			case distance > limit:
+				continue
+			case distance < limit:
+				// Kandidat yang lebih dekat menggantikan yang sudah ditemukan
+				limit = distance
+				candidates = candidates[:0]
+			}
+			if !slices.Contains(candidates, word) {
+				candidates = append(candidates, word)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		sort.Strings(candidates)
+		for _, candidate := range candidates {
+			corrected.fuzzy[candidate] = i
+		}
+		corrected.corrections[i] = candidates[0]
+	}
+	return corrected
+}
+
+// Corrections adalah term pengganti hasil Correct, untuk mengambil posting-nya dari indeks
+func (q Query) Corrections() []string {
+	terms := make([]string, 0, len(q.fuzzy))
+	for term := range q.fuzzy {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// DidYouMean adalah teks query dengan term yang salah ketik diganti pengganti terbaiknya,
+// kosong jika tidak ada term yang dikoreksi
+func (q Query) DidYouMean() string {
+	if len(q.corrections) == 0 {
+		return ""
+	}
+	terms := make([]string, len(q.Terms))
+	for i, term := range q.Terms {
+		if best, ok := q.corrections[i]; ok {
+			term = best
+		}
+		terms[i] = term
+	}
+	return strings.Join(terms, " ")
+}
+
+// editDistance menghitung jarak Damerau-Levenshtein (optimal string alignment) antara a dan b:
+// sisip, hapus, ganti, atau tukar dua huruf bersebelahan masing-masing satu edit. Berhenti lebih
+// awal dan mengembalikan limit+1 jika jaraknya pasti melebihi limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	// Tiga baris terakhir tabel dynamic programming cukup untuk transposisi
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	prevMin := 0
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		// Transposisi bisa memakai baris sebelumnya, jadi berhenti jika dua baris terakhir lewat batas
+		if rowMin > limit && prevMin > limit {
+			return limit + 1
+		}
+		prevMin = rowMin
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
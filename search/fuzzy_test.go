@@ -0,0 +1,78 @@
+package search
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"equal", "orwell", "orwell", 2, 0},
+		{"insert", "orwel", "orwell", 2, 1},
+		{"delete", "orwelll", "orwell", 2, 1},
+		{"substitute", "orwall", "orwell", 2, 1},
+		{"transposition", "orwlel", "orwell", 2, 1},
+		{"transposition at start", "rowell", "orwell", 2, 1},
+		{"two edits", "orwal", "orwell", 2, 2},
+		{"multibyte", "café", "cafe", 2, 1},
+		{"over limit", "orwell", "tolkien", 2, 3},
+		{"length over limit", "abc", "abcdefg", 2, 3},
+		{"zero limit", "orwel", "orwell", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+				t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxEdits(t *testing.T) {
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"", 0},
+		{"tom", 0},
+		{"toms", 1},
+		{"orwell", 1},
+		{"tolkiens", 2},
+		{"pramoedya", 2},
+		{"éèêë", 1},
+	}
+	for _, tt := range tests {
+		if got := MaxEdits(tt.term); got != tt.want {
+			t.Errorf("MaxEdits(%q) = %d, want %d", tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestCorrect(t *testing.T) {
+	vocabulary := []string{"orwell", "orchard", "pramoedya", "tom", "tolkien", "rowling"}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"one edit", "orwel", "orwell"},
+		{"transposition", "owrell", "orwell"},
+		{"swapped first letters", "rowell", "orwell"},
+		{"two edits on long term", "pramudya", "pramoedya"},
+		{"two edits on short term", "orwal", ""},
+		{"short term not corrected", "tim", ""},
+		{"typo in first letter", "xrwell", ""},
+		{"no candidate", "zzzzzz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseQuery(tt.query).Correct([]int{0}, vocabulary).DidYouMean()
+			if got != tt.want {
+				t.Errorf("Correct(%q) did you mean = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
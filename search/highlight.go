@@ -0,0 +1,68 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// contextWords adalah jumlah kata sebelum kecocokan pertama yang ikut masuk potongan teks
+const contextWords = 4
+
+// Highlight mengembalikan potongan text paling banyak maxLen karakter di sekitar kecocokan pertama
+// dengan query. Teks di-escape sebagai HTML, kata yang cocok dibungkus <mark>, dan "…" menandai
+// teks yang dipotong. ok false jika tidak ada kata yang cocok.
+func Highlight(text string, q Query, maxLen int) (snippet string, ok bool) {
+	if q.Empty() {
+		return "", false
+	}
+
+	all := words(text)
+	matched := make([]bool, len(all))
+	first := -1
+	for i, w := range all {
+		if idx, _ := q.match(w.term); idx >= 0 {
+			matched[i] = true
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > maxLen {
+		startWord := max(0, first-contextWords)
+		from = all[startWord].start
+		to = all[first].end
+		for _, w := range all[startWord:] {
+			if utf8.RuneCountInString(text[from:w.end]) > maxLen {
+				break
+			}
+			to = max(to, w.end)
+		}
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for i, w := range all {
+		if !matched[i] || w.start < from || w.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[w.start:w.end]))
+		b.WriteString("</mark>")
+		pos = w.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
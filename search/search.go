@@ -0,0 +1,283 @@
+// Package search adalah mesin pencarian teks untuk katalog: tokenisasi, posting untuk inverted
+// index per term dan field, ranking BM25, dan potongan teks dengan kata yang cocok disorot.
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Field yang diindeks
+const (
+	FieldTitle       = "title"
+	FieldAuthor      = "author"
+	FieldPublisher   = "publisher"
+	FieldDescription = "description"
+)
+
+// MaxTermLength adalah panjang maksimum term dalam byte, sesuai kolom term di tabel indeks.
+// Term yang lebih panjang dipotong.
+const MaxTermLength = 64
+
+// FieldWeights adalah bobot tiap field; kecocokan di judul lebih relevan daripada di deskripsi
+var FieldWeights = map[string]float64{
+	FieldTitle:       3,
+	FieldAuthor:      2,
+	FieldPublisher:   1,
+	FieldDescription: 1,
+}
+
+const (
+	// k1 mengatur seberapa cepat skor jenuh saat term muncul berulang di satu field
+	k1 = 1.2
+	// prefixWeight untuk term terakhir yang cocok sebagai awalan, bukan kata utuh
+	prefixWeight = 0.5
+)
+
+// Document adalah teks per field milik satu dokumen
+type Document struct {
+	ID     uint
+	Fields map[string]string
+}
+
+// Posting mencatat berapa kali Term muncul di Field milik dokumen DocID
+type Posting struct {
+	DocID     uint
+	Term      string
+	Field     string
+	Frequency int
+}
+
+// Postings memecah semua field dokumen menjadi posting untuk disimpan di inverted index
+func (d Document) Postings() []Posting {
+	var postings []Posting
+	for field, text := range d.Fields {
+		counts := map[string]int{}
+		var order []string
+		for _, term := range Tokens(text) {
+			if counts[term] == 0 {
+				order = append(order, term)
+			}
+			counts[term]++
+		}
+		for _, term := range order {
+			postings = append(postings, Posting{DocID: d.ID, Term: term, Field: field, Frequency: counts[term]})
+		}
+	}
+	return postings
+}
+
+// fold membuang tanda diakritik supaya "Pramoedya" cocok dengan "Pramoédya"
+var fold = transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+
+// normalize mengubah satu kata ke bentuk term: huruf kecil tanpa diakritik
+func normalize(word string) string {
+	folded, _, err := transform.String(fold, word)
+	if err != nil {
+		folded = word
+	}
+	term := strings.ToLower(folded)
+	if len(term) > MaxTermLength {
+		term = term[:MaxTermLength]
+		// jangan sampai pemotongan memutus karakter multibyte
+		for !utf8.ValidString(term) {
+			term = term[:len(term)-1]
+		}
+	}
+	return term
+}
+
+// Tokens memecah teks menjadi term: rangkaian huruf atau angka, huruf kecil, tanpa diakritik
+func Tokens(text string) []string {
+	var terms []string
+	for _, w := range words(text) {
+		terms = append(terms, w.term)
+	}
+	return terms
+}
+
+type word struct {
+	start, end int
+	term       string
+}
+
+// words mencari setiap kata di text beserta posisi byte-nya, dipakai juga untuk highlight
+func words(text string) []word {
+	var result []word
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			result = append(result, word{start: start, end: i, term: normalize(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		result = append(result, word{start: start, end: len(text), term: normalize(text[start:])})
+	}
+	return result
+}
+
+// Query adalah teks pencarian yang sudah dipecah menjadi term. Semua term harus cocok, term
+// terakhir juga cocok sebagai awalan supaya pencarian "harry pot" sudah menemukan "Harry Potter".
+type Query struct {
+	Terms []string
+	// fuzzy memetakan term pengganti hasil Correct ke indeks term query yang digantikannya
+	fuzzy map[string]int
+	// corrections adalah pengganti terbaik per indeks term query, untuk DidYouMean
+	corrections map[int]string
+}
+
+func ParseQuery(text string) Query {
+	seen := map[string]bool{}
+	var terms []string
+	for _, term := range Tokens(text) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return Query{Terms: terms}
+}
+
+func (q Query) Empty() bool {
+	return len(q.Terms) == 0
+}
+
+// Prefix adalah term terakhir yang juga dicocokkan sebagai awalan
+func (q Query) Prefix() string {
+	if q.Empty() {
+		return ""
+	}
+	return q.Terms[len(q.Terms)-1]
+}
+
+// match mengembalikan indeks term query yang cocok dengan term dokumen dan bobot kecocokannya,
+// -1 jika tidak cocok
+func (q Query) match(term string) (int, float64) {
+	for i, queryTerm := range q.Terms {
+		if term == queryTerm {
+			return i, 1
+		}
+	}
+	if prefix := q.Prefix(); prefix != "" && strings.HasPrefix(term, prefix) {
+		return len(q.Terms) - 1, prefixWeight
+	}
+	if i, ok := q.fuzzy[term]; ok {
+		return i, fuzzyWeight
+	}
+	return -1, 0
+}
+
+// Index mengembalikan indeks term query yang dipenuhi term dokumen, -1 jika tidak cocok. Setiap term
+// dokumen hanya memenuhi satu term query: kata utuh didahulukan, lalu awalan, lalu hasil koreksi.
+func (q Query) Index(term string) int {
+	i, _ := q.match(term)
+	return i
+}
+
+// Matches mengecek apakah setiap term query cocok dengan salah satu kata di text
+func (q Query) Matches(text string) bool {
+	if q.Empty() {
+		return false
+	}
+	found := make([]bool, len(q.Terms))
+	for _, w := range words(text) {
+		if i, _ := q.match(w.term); i >= 0 {
+			found[i] = true
+		}
+	}
+	for _, ok := range found {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Hit adalah dokumen yang cocok dengan query beserta skor relevansinya
+type Hit struct {
+	DocID uint
+	Score float64
+}
+
+// IDF adalah bobot BM25 untuk term yang muncul di df dari totalDocs dokumen; makin jarang makin
+// besar. Skor satu kecocokan di field berbobot 1 sama dengan IDF-nya.
+func IDF(df, totalDocs int) float64 {
+	// posting bisa berasal dari dokumen yang tidak dihitung di totalDocs, misalnya yang di trash
+	n := float64(max(totalDocs, df))
+	return math.Log(1 + (n-float64(df)+0.5)/(float64(df)+0.5))
+}
+
+// Rank menghitung skor BM25 setiap dokumen dari posting yang cocok dengan query. totalDocs adalah
+// jumlah dokumen di indeks untuk menghitung IDF; panjang field tidak dinormalisasi karena judul dan
+// nama penulis pendek semua. Hanya dokumen yang cocok dengan semua term query yang dikembalikan,
+// urut dari skor tertinggi lalu DocID terbaru.
+func Rank(q Query, postings []Posting, totalDocs int) []Hit {
+	if q.Empty() {
+		return nil
+	}
+
+	// docs[i] berisi dokumen yang cocok dengan term query ke-i, untuk IDF dan syarat semua term
+	docs := make([]map[uint]bool, len(q.Terms))
+	for i := range docs {
+		docs[i] = map[uint]bool{}
+	}
+	type scored struct {
+		posting Posting
+		term    int
+		weight  float64
+	}
+	var matched []scored
+	for _, posting := range postings {
+		i, weight := q.match(posting.Term)
+		if i < 0 {
+			continue
+		}
+		docs[i][posting.DocID] = true
+		matched = append(matched, scored{posting, i, weight})
+	}
+
+	idf := make([]float64, len(q.Terms))
+	for i, set := range docs {
+		idf[i] = IDF(len(set), totalDocs)
+	}
+
+	scores := map[uint]float64{}
+	for _, m := range matched {
+		tf := float64(m.posting.Frequency)
+		scores[m.posting.DocID] += FieldWeights[m.posting.Field] * m.weight * idf[m.term] * tf * (k1 + 1) / (tf + k1)
+	}
+
+	var hits []Hit
+	for id, score := range scores {
+		all := true
+		for _, set := range docs {
+			if !set[id] {
+				all = false
+				break
+			}
+		}
+		if all {
+			hits = append(hits, Hit{DocID: id, Score: score})
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].DocID > hits[j].DocID
+	})
+	return hits
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"backend_perpustakaan_online/repositories"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Worker adalah proses latar yang berjalan selama server hidup dan harus berhenti saat ctx selesai
+type Worker func(ctx context.Context)
+
+// Run menjalankan app dan workers di addr sampai ctx selesai (biasanya karena SIGINT/SIGTERM), lalu
+// menunggu semua worker berhenti sebelum memanggil Shutdown, supaya koneksi database tidak ditutup
+// saat worker masih di tengah transaksi. Error dari Listen dikembalikan apa adanya, misalnya port
+// sudah dipakai.
+func Run(ctx context.Context, app *fiber.App, addr string, db *gorm.DB, shutdownTimeout time.Duration, workers ...Worker) error {
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	defer stopWorkers()
+	var running sync.WaitGroup
+	for _, worker := range workers {
+		running.Go(func() {
+			worker(workerCtx)
+		})
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- app.Listen(addr)
+	}()
+
+	select {
+	case err := <-listenErr:
+		stopWorkers()
+		running.Wait()
+		closeDB(db)
+		return err
+	case <-ctx.Done():
+		log.Println("Sinyal berhenti diterima, menunggu request yang sedang berjalan selesai...")
+	}
+
+	running.Wait()
+	if err := Shutdown(app, db, shutdownTimeout); err != nil {
+		return err
+	}
+	return <-listenErr
+}
+
+// Shutdown berhenti menerima koneksi baru, menunggu request yang sedang berjalan selesai paling lama
+// timeout, lalu menutup koneksi database. DB tetap ditutup walaupun draining melewati timeout.
+func Shutdown(app *fiber.App, db *gorm.DB, timeout time.Duration) error {
+	shutdownErr := app.ShutdownWithTimeout(timeout)
+	if errors.Is(shutdownErr, context.DeadlineExceeded) {
+		log.Printf("Request masih berjalan setelah %s, koneksi diputus paksa", timeout)
+	}
+
+	if err := closeDB(db); err != nil {
+		return errors.Join(shutdownErr, err)
+	}
+
+	log.Println("Server berhenti")
+	return shutdownErr
+}
+
+func closeDB(db *gorm.DB) error {
+	if db == nil {
+		return nil
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// ExpireHolds secara berkala menutup hold ready yang tidak diambil dalam batas waktu pickup,
+// berhenti saat ctx selesai
+func ExpireHolds(ctx context.Context, holdRepo *repositories.HoldRepository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		expired, err := holdRepo.ExpireOverdue(time.Now())
+		if err != nil {
+			log.Printf("Failed to expire holds: %v", err)
+			continue
+		}
+		if expired > 0 {
+			log.Printf("Expired %d uncollected holds", expired)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/handlers"
+	"backend_perpustakaan_online/middleware"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofiber/fiber/v2/utils"
+	"gorm.io/gorm"
+)
+
+// requestIDKey adalah key c.Locals tempat request ID disimpan oleh middleware requestid
+const requestIDKey = "requestid"
+
+// Config adalah dependency yang dibutuhkan New
+type Config struct {
+	AppName string
+	// DB dipakai semua repository. Nil berarti demo mode tanpa database.
+	DB *gorm.DB
+	// Books adalah store katalog buku, default BookRepository di atas DB
+	Books repositories.BookStore
+}
+
+// New menyusun Fiber app beserta middleware dan semua route. Tanpa cfg.DB (demo mode) hanya route
+// katalog buku yang didaftarkan karena route lain butuh database.
+func New(cfg Config) *fiber.App {
+	db := cfg.DB
+	bookStore := cfg.Books
+	if bookStore == nil {
+		bookStore = repositories.NewBookRepository(db)
+	}
+
+	appName := cfg.AppName
+	if appName == "" {
+		appName = "Library API"
+	}
+
+	// Initialize Fiber app
+	app := fiber.New(fiber.Config{
+		AppName:      appName,
+		ErrorHandler: errorHandler,
+	})
+
+	// Middleware. Request ID diambil dari header X-Request-ID jika klien mengirimnya, atau dibuat baru,
+	// lalu dikembalikan di header respons, di log, dan di setiap respons error.
+	app.Use(requestid.New(requestid.Config{ContextKey: requestIDKey}))
+	app.Use(logger.New(logger.Config{
+		Format: "${time} | ${status} | ${latency} | ${ip} | ${method} | ${path} | ${locals:requestid} | ${error}\n",
+	}))
+	app.Use(recover.New())
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE",
+		AllowHeaders:  "Content-Type,Authorization,X-Request-ID,If-Match,If-None-Match",
+		ExposeHeaders: "X-Request-ID,ETag",
+	}))
+
+	bookHandler := handlers.NewBookHandler(bookStore)
+
+	api := app.Group("/api")
+
+	// Tanpa database token tidak bisa dicek ke tabel users
+	var userRepo *repositories.UserRepository
+	if db != nil {
+		userRepo = repositories.NewUserRepository(db)
+	}
+	protected := middleware.Protected(userRepo)
+	adminOnly := middleware.RequireRole(models.RoleAdmin)
+
+	books := api.Group("/books")
+	books.Get("/", bookHandler.GetAllBooks)
+	books.Get("/suggest", bookHandler.SuggestBooks)
+	books.Get("/trash", protected, adminOnly, bookHandler.GetDeletedBooks)
+	books.Delete("/trash", protected, adminOnly, bookHandler.PurgeBooks)
+	books.Get("/:id", bookHandler.GetBookByID)
+	books.Post("/", protected, adminOnly, bookHandler.CreateBook)
+	books.Put("/:id", protected, adminOnly, bookHandler.UpdateBook)
+	books.Patch("/:id", protected, adminOnly, bookHandler.PatchBook)
+	books.Delete("/:id", protected, adminOnly, bookHandler.DeleteBook)
+	books.Patch("/:id/status", protected, adminOnly, bookHandler.UpdateBookStatus)
+	books.Post("/:id/restore", protected, adminOnly, bookHandler.RestoreBook)
+
+	if db != nil {
+		copyRepo := repositories.NewBookCopyRepository(db)
+		loanRepo := repositories.NewLoanRepository(db)
+		holdRepo := repositories.NewHoldRepository(db)
+		fineRepo := repositories.NewFineRepository(db)
+
+		authHandler := handlers.NewAuthHandler(userRepo)
+		userHandler := handlers.NewUserHandler(userRepo)
+		loanHandler := handlers.NewLoanHandler(loanRepo, userRepo, copyRepo, fineRepo)
+		copyHandler := handlers.NewBookCopyHandler(bookStore, copyRepo)
+		holdHandler := handlers.NewHoldHandler(holdRepo, userRepo)
+		fineHandler := handlers.NewFineHandler(fineRepo)
+
+		auth := api.Group("/auth")
+		auth.Post("/register", authHandler.Register)
+		auth.Post("/login", authHandler.Login)
+		auth.Post("/refresh", authHandler.Refresh)
+
+		books.Get("/:id/copies", copyHandler.GetBookCopies)
+		books.Post("/:id/copies", protected, adminOnly, copyHandler.CreateBookCopy)
+		books.Get("/:id/holds", protected, holdHandler.GetBookHolds)
+		books.Post("/:id/holds", protected, holdHandler.CreateBookHold)
+		books.Delete("/:id/holds/:holdId", protected, holdHandler.CancelBookHold)
+
+		copies := api.Group("/copies", protected, adminOnly)
+		copies.Put("/:id", copyHandler.UpdateBookCopy)
+		copies.Patch("/:id/status", copyHandler.UpdateBookCopyStatus)
+		copies.Delete("/:id", copyHandler.DeleteBookCopy)
+
+		users := api.Group("/users", protected, adminOnly)
+		users.Get("/", userHandler.GetAllUsers)
+		users.Get("/:id", userHandler.GetUserByID)
+		users.Post("/", userHandler.CreateUser)
+		users.Patch("/:id/role", userHandler.UpdateUserRole)
+		users.Patch("/:id/active", userHandler.UpdateUserActive)
+		users.Delete("/:id", userHandler.DeleteUser)
+
+		loans := api.Group("/loans", protected)
+		loans.Get("/", loanHandler.GetAllLoans)
+		loans.Get("/:id", loanHandler.GetLoanByID)
+		loans.Post("/", loanHandler.CreateLoan)
+		loans.Post("/:id/return", adminOnly, loanHandler.ReturnLoan)
+		loans.Post("/:id/renew", loanHandler.RenewLoan)
+
+		fines := api.Group("/fines", protected)
+		fines.Get("/", fineHandler.GetAllFines)
+		fines.Get("/balance", fineHandler.GetBalance)
+		fines.Get("/:id", fineHandler.GetFineByID)
+		fines.Post("/:id/payments", adminOnly, fineHandler.RecordPayment)
+		fines.Post("/:id/waive", adminOnly, fineHandler.WaiveFine)
+	}
+
+	app.Get("/health", healthCheck(db))
+
+	app.Use(func(c *fiber.Ctx) error {
+		return apperror.NotFound("route_not_found", "Endpoint tidak ada")
+	})
+
+	return app
+}
+
+// healthCheck melaporkan status API dan koneksi database
+func healthCheck(db *gorm.DB) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if db == nil {
+			return c.JSON(fiber.Map{
+				"status":   "ok",
+				"message":  "Library API berjalan",
+				"database": "disabled (demo mode)",
+			})
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Koneksi database gagal",
+			})
+		}
+
+		if err := sqlDB.Ping(); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"status":  "error",
+				"message": "Ping database gagal",
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"status":   "ok",
+			"message":  "Library API berjalan",
+			"database": "connected",
+		})
+	}
+}
+
+// errorResponse adalah envelope seragam semua respons error
+type errorResponse struct {
+	Success   bool      `json:"success"`
+	Error     errorBody `json:"error"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// errorHandler adalah satu-satunya tempat error dari handler dan middleware diubah menjadi HTTP status
+// dan errorResponse. Error tak terduga dicatat ke log beserta request ID, klien hanya menerima pesan generik.
+func errorHandler(c *fiber.Ctx, err error) error {
+	status, body := resolveError(err)
+
+	requestID, _ := c.Locals(requestIDKey).(string)
+	if status >= fiber.StatusInternalServerError {
+		log.Printf("request %s %s %s gagal: %v", requestID, c.Method(), c.OriginalURL(), err)
+	}
+
+	return c.Status(status).JSON(errorResponse{
+		Success:   false,
+		Error:     body,
+		RequestID: requestID,
+	})
+}
+
+func resolveError(err error) (int, errorBody) {
+	var appErr *apperror.Error
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &appErr):
+	case errors.As(err, &fiberErr):
+		// Error bawaan Fiber, misalnya 405 atau body terlalu besar
+		return fiberErr.Code, errorBody{Code: statusCode(fiberErr.Code), Message: fiberErr.Message}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		appErr = apperror.NotFound("not_found", "Data tidak ada")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		appErr = apperror.Conflict("duplicate", "Data sudah ada")
+	default:
+		appErr = apperror.Internal(err)
+	}
+
+	return appErr.Status(), errorBody{Code: appErr.Code, Message: appErr.Message, Details: appErr.Details}
+}
+
+// statusCode membuat kode error dari nama HTTP status, misalnya 405 menjadi method_not_allowed
+func statusCode(status int) string {
+	return strings.ToLower(strings.ReplaceAll(utils.StatusMessage(status), " ", "_"))
+}
@@ -0,0 +1,1900 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"backend_perpustakaan_online/config"
+	"backend_perpustakaan_online/database"
+	"backend_perpustakaan_online/models"
+	"backend_perpustakaan_online/repositories"
+)
+
+func TestMain(m *testing.M) {
+	if err := config.InitJWT(); err != nil {
+		fmt.Println("init JWT:", err)
+		os.Exit(1)
+	}
+	config.InitLibrary()
+
+	os.Exit(m.Run())
+}
+
+// testStores adalah backend yang dipakai setiap test; suite yang sama dijalankan ke SQLite
+// (route lengkap seperti production) dan ke MemoryBookStore (route demo mode)
+var testStores = []struct {
+	name  string
+	setup func(t *testing.T) *fiber.App
+}{
+	{"sqlite", newSQLiteApp},
+	{"memory", newMemoryApp},
+}
+
+func newSQLiteApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return New(Config{DB: newSQLiteDB(t)})
+}
+
+// newSQLiteDB membuka database SQLite baru di direktori sementara yang sudah dimigrasi
+func newSQLiteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := config.SQLiteDSN(filepath.Join(t.TempDir(), "test.db"))
+	db, err := config.OpenDB(sqlite.Open(dsn), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("prepare migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+
+	// User pemilik token dari tokenFor, karena Protected mengecek user ke database
+	for _, role := range []models.UserRole{models.RoleAdmin, models.RoleUser} {
+		user := models.User{ID: testUserIDs[role], Name: string(role), Email: string(role) + "@test.local", Password: "secret123", Role: role, IsActive: true}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("create %s user: %v", role, err)
+		}
+	}
+
+	return db
+}
+
+func newMemoryApp(t *testing.T) *fiber.App {
+	t.Helper()
+	return New(Config{Books: repositories.NewMemoryBookStore()})
+}
+
+// forEachStore menjalankan test untuk setiap backend dengan app baru yang kosong
+func forEachStore(t *testing.T, test func(t *testing.T, app *fiber.App)) {
+	for _, store := range testStores {
+		t.Run(store.name, func(t *testing.T) {
+			test(t, store.setup(t))
+		})
+	}
+}
+
+type apiResponse struct {
+	Status int
+	Header http.Header
+	Body   map[string]any
+}
+
+func (r apiResponse) data() map[string]any {
+	data, _ := r.Body["data"].(map[string]any)
+	return data
+}
+
+func (r apiResponse) list() []any {
+	list, _ := r.Body["data"].([]any)
+	return list
+}
+
+func (r apiResponse) meta() map[string]any {
+	meta, _ := r.Body["meta"].(map[string]any)
+	return meta
+}
+
+// errorCode adalah error.code dari envelope error
+func (r apiResponse) errorCode() string {
+	body, _ := r.Body["error"].(map[string]any)
+	code, _ := body["code"].(string)
+	return code
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, body any, token string) apiResponse {
+	t.Helper()
+	return doRequestWithHeaders(t, app, method, path, body, token, nil)
+}
+
+// doWithIfMatch mengirim request dengan If-Match berisi ETag book saat ini, diambil lewat GET path
+func doWithIfMatch(t *testing.T, app *fiber.App, method, path string, body any, token string) apiResponse {
+	t.Helper()
+	etag := doRequest(t, app, http.MethodGet, path, nil, "").Header.Get("ETag")
+	return doRequestWithHeaders(t, app, method, path, body, token, map[string]string{"If-Match": etag})
+}
+
+func doRequestWithHeaders(t *testing.T, app *fiber.App, method, path string, body any, token string, headers map[string]string) apiResponse {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read body: %v", err)
+	}
+
+	result := apiResponse{Status: resp.StatusCode, Header: resp.Header}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &result.Body); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q", method, path, raw)
+		}
+	}
+	return result
+}
+
+func expectStatus(t *testing.T, resp apiResponse, want int) {
+	t.Helper()
+	if resp.Status != want {
+		t.Fatalf("status = %d, want %d (body %v)", resp.Status, want, resp.Body)
+	}
+}
+
+// expectError memastikan respons memakai envelope error dengan status, kode, dan request ID
+func expectError(t *testing.T, resp apiResponse, status int, code string) {
+	t.Helper()
+	expectStatus(t, resp, status)
+	if resp.Body["success"] != false {
+		t.Errorf("success = %v, want false", resp.Body["success"])
+	}
+	if got := resp.errorCode(); got != code {
+		t.Errorf("error.code = %q, want %q (body %v)", got, code, resp.Body)
+	}
+	if id, _ := resp.Body["request_id"].(string); id == "" {
+		t.Errorf("request_id is empty (body %v)", resp.Body)
+	}
+}
+
+// testUserIDs adalah ID user untuk setiap role; di SQLite user-nya dibuat oleh newSQLiteDB
+var testUserIDs = map[models.UserRole]uint{models.RoleAdmin: 1, models.RoleUser: 2}
+
+func tokenFor(t *testing.T, role models.UserRole) string {
+	t.Helper()
+	token, err := config.JWT.GenerateToken(testUserIDs[role], string(role)+"@test.local", role)
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	return token
+}
+
+// createMember membuat member tambahan di database, untuk test yang butuh lebih dari satu member
+func createMember(t *testing.T, db *gorm.DB, name string) uint {
+	t.Helper()
+	user := models.User{Name: name, Email: name + "@test.local", Password: "secret123", Role: models.RoleUser, IsActive: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create member %s: %v", name, err)
+	}
+	return user.ID
+}
+
+func bookPayload(n int) map[string]any {
+	return map[string]any{
+		"title":    fmt.Sprintf("Book %02d", n),
+		"author":   fmt.Sprintf("Author %02d", n),
+		"isbn":     isbn13(n),
+		"category": "Fiction",
+	}
+}
+
+// isbn13 membuat ISBN-13 yang lolos checksum untuk buku ke-n
+func isbn13(n int) string {
+	digits := fmt.Sprintf("978000000%03d", n)
+	sum := 0
+	for i, d := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return fmt.Sprintf("978-000000%03d-%d", n, (10-sum%10)%10)
+}
+
+// patchStatus mengubah status book lewat PATCH /status dengan If-Match dari ETag book saat ini
+func patchStatus(t *testing.T, app *fiber.App, book map[string]any, status, token string) apiResponse {
+	t.Helper()
+	etag := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "").Header.Get("ETag")
+	return doRequestWithHeaders(t, app, http.MethodPatch, bookPath(book, "/status"), map[string]any{"status": status}, token, map[string]string{"If-Match": etag})
+}
+
+func createBook(t *testing.T, app *fiber.App, payload map[string]any) map[string]any {
+	t.Helper()
+	resp := doRequest(t, app, http.MethodPost, "/api/books", payload, tokenFor(t, models.RoleAdmin))
+	expectStatus(t, resp, http.StatusCreated)
+	return resp.data()
+}
+
+func bookPath(book map[string]any, suffix string) string {
+	return fmt.Sprintf("/api/books/%v%s", book["id"], suffix)
+}
+
+func TestCreateBook(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+
+		payload := bookPayload(1)
+		payload["copies"] = 3
+		resp := doRequest(t, app, http.MethodPost, "/api/books", payload, admin)
+		expectStatus(t, resp, http.StatusCreated)
+
+		book := resp.data()
+		if book["title"] != "Book 01" || book["isbn"] != strings.ReplaceAll(isbn13(1), "-", "") {
+			t.Errorf("created book = %v", book)
+		}
+		if book["status"] != models.BookStatusAvailable {
+			t.Errorf("status = %v, want available", book["status"])
+		}
+		if book["total_copies"] != float64(3) || book["available_copies"] != float64(3) {
+			t.Errorf("copies = %v/%v, want 3/3", book["available_copies"], book["total_copies"])
+		}
+
+		tests := []struct {
+			name    string
+			payload map[string]any
+			token   string
+			want    int
+			code    string
+		}{
+			{"duplicate isbn", bookPayload(1), admin, http.StatusConflict, "isbn_exists"},
+			{"missing title", map[string]any{"author": "A", "isbn": isbn13(2)}, admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"missing isbn", map[string]any{"title": "T", "author": "A"}, admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"isbn checksum", withField(bookPayload(2), "isbn", "978-0-306-40615-6"), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"isbn length", withField(bookPayload(2), "isbn", "978-045228423"), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"isbn-10 with X", withField(bookPayload(3), "isbn", "0-8044-2957-X"), admin, http.StatusCreated, ""},
+			{"future publisher_at", withField(bookPayload(2), "publisher_at", time.Now().AddDate(1, 0, 0)), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"too many pages", withField(bookPayload(2), "total_pages", 10001), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"negative pages", withField(bookPayload(2), "total_pages", -5), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"borrowed status", withField(bookPayload(2), "status", "borrowed"), admin, http.StatusBadRequest, "status_borrowed_via_loans"},
+			{"unknown status", withField(bookPayload(2), "status", "lost"), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"too many copies", withField(bookPayload(2), "copies", 101), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"negative copies", withField(bookPayload(2), "copies", -1), admin, http.StatusUnprocessableEntity, "validation_failed"},
+			{"without token", bookPayload(2), "", http.StatusUnauthorized, "missing_token"},
+			{"invalid token", bookPayload(2), "not-a-jwt", http.StatusUnauthorized, "invalid_token"},
+			{"member token", bookPayload(2), tokenFor(t, models.RoleUser), http.StatusForbidden, "insufficient_role"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodPost, "/api/books", tt.payload, tt.token)
+				if tt.want == http.StatusCreated {
+					expectStatus(t, resp, tt.want)
+					return
+				}
+				expectError(t, resp, tt.want, tt.code)
+			})
+		}
+
+		t.Run("field details", func(t *testing.T) {
+			payload := map[string]any{"author": "A", "isbn": "12345", "total_pages": 20000}
+			resp := doRequest(t, app, http.MethodPost, "/api/books", payload, admin)
+			expectError(t, resp, http.StatusUnprocessableEntity, "validation_failed")
+
+			errBody, _ := resp.Body["error"].(map[string]any)
+			details, _ := errBody["details"].([]any)
+			rules := map[string]string{}
+			for _, d := range details {
+				detail, _ := d.(map[string]any)
+				field, _ := detail["field"].(string)
+				rules[field], _ = detail["rule"].(string)
+				if msg, _ := detail["message"].(string); msg == "" {
+					t.Errorf("detail %v has no message", detail)
+				}
+			}
+			want := map[string]string{"title": "required", "isbn": "isbn", "total_pages": "max"}
+			if len(rules) != len(want) {
+				t.Errorf("details = %v, want fields %v", details, want)
+			}
+			for field, rule := range want {
+				if rules[field] != rule {
+					t.Errorf("details[%s].rule = %q, want %q", field, rules[field], rule)
+				}
+			}
+		})
+
+		t.Run("invalid body", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/books", bytes.NewReader([]byte("{")))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+admin)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", resp.StatusCode)
+			}
+		})
+	})
+}
+
+func TestISBNNormalization(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+
+		book := createBook(t, app, withField(bookPayload(1), "isbn", "0-8044-2957-x"))
+		if book["isbn"] != "9780804429573" {
+			t.Errorf("isbn = %v, want ISBN-13 9780804429573", book["isbn"])
+		}
+		other := createBook(t, app, bookPayload(2))
+
+		for _, form := range []string{"978-0-8044-2957-3", "9780804429573", "080442957X"} {
+			t.Run("duplicate "+form, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodPost, "/api/books", withField(bookPayload(3), "isbn", form), admin)
+				expectError(t, resp, http.StatusConflict, "isbn_exists")
+
+				resp = doWithIfMatch(t, app, http.MethodPatch, bookPath(other, ""), map[string]any{"isbn": form}, admin)
+				expectError(t, resp, http.StatusConflict, "isbn_exists")
+			})
+		}
+
+		for _, search := range []string{"0-8044-2957-X", "978-0-8044"} {
+			resp := doRequest(t, app, http.MethodGet, "/api/books?search="+search, nil, "")
+			expectStatus(t, resp, http.StatusOK)
+			if resp.meta()["total"] != float64(1) {
+				t.Errorf("search %q total = %v, want 1", search, resp.meta()["total"])
+			}
+		}
+	})
+}
+
+func TestGetAllBooksPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		for i := 1; i <= 15; i++ {
+			payload := bookPayload(i)
+			if i%3 == 0 {
+				payload["category"] = "Science"
+			}
+			createBook(t, app, payload)
+		}
+
+		tests := []struct {
+			name      string
+			query     string
+			wantItems int
+			wantMeta  map[string]float64
+		}{
+			{"default page", "", 10, map[string]float64{"page": 1, "limit": 10, "total": 15, "total_page": 2}},
+			{"second page", "?page=2&limit=10", 5, map[string]float64{"page": 2, "limit": 10, "total": 15, "total_page": 2}},
+			{"custom limit", "?limit=4&page=4", 3, map[string]float64{"page": 4, "limit": 4, "total": 15, "total_page": 4}},
+			{"page past end", "?page=9", 0, map[string]float64{"page": 9, "limit": 10, "total": 15, "total_page": 2}},
+			{"invalid paging falls back", "?page=0&limit=-5", 10, map[string]float64{"page": 1, "limit": 10, "total": 15, "total_page": 2}},
+			{"category filter", "?category=Science", 5, map[string]float64{"total": 5, "total_page": 1}},
+			{"search title", "?search=book%2012", 1, map[string]float64{"total": 1}},
+			{"search author case insensitive", "?search=AUTHOR%2007", 1, map[string]float64{"total": 1}},
+			{"search isbn", "?search=00000015", 1, map[string]float64{"total": 1}},
+			{"status filter", "?status=maintenance", 0, map[string]float64{"total": 0, "total_page": 0}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodGet, "/api/books"+tt.query, nil, "")
+				expectStatus(t, resp, http.StatusOK)
+
+				if got := len(resp.list()); got != tt.wantItems {
+					t.Errorf("items = %d, want %d", got, tt.wantItems)
+				}
+				meta := resp.meta()
+				for key, want := range tt.wantMeta {
+					if meta[key] != want {
+						t.Errorf("meta.%s = %v, want %v", key, meta[key], want)
+					}
+				}
+			})
+		}
+
+		t.Run("pages do not overlap", func(t *testing.T) {
+			seen := map[any]bool{}
+			for page := 1; page <= 2; page++ {
+				resp := doRequest(t, app, http.MethodGet, fmt.Sprintf("/api/books?page=%d", page), nil, "")
+				for _, item := range resp.list() {
+					id := item.(map[string]any)["id"]
+					if seen[id] {
+						t.Fatalf("book %v returned on more than one page", id)
+					}
+					seen[id] = true
+				}
+			}
+			if len(seen) != 15 {
+				t.Errorf("books across pages = %d, want 15", len(seen))
+			}
+		})
+	})
+}
+
+func TestSearchBooks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+
+		inDescription := createBook(t, app, withField(withField(bookPayload(1), "title", "Catatan Perjalanan"),
+			"description", "Kisah perjalanan ke laut selatan bersama seekor paus putih yang legendaris."))
+		inTitle := createBook(t, app, withField(withField(bookPayload(2), "title", "Paus Putih"), "author", "Herman Melville"))
+		createBook(t, app, withField(withField(bookPayload(3), "title", "Bumi Manusia"), "author", "Pramoedya Ananta Toer"))
+		createBook(t, app, withField(withField(bookPayload(4), "title", "Laskar Pelangi"), "publisher", "Bentang Pustaka"))
+
+		search := func(t *testing.T, query string) apiResponse {
+			t.Helper()
+			resp := doRequest(t, app, http.MethodGet, "/api/books?search="+url.QueryEscape(query), nil, "")
+			expectStatus(t, resp, http.StatusOK)
+			return resp
+		}
+
+		t.Run("title match ranks above description match", func(t *testing.T) {
+			resp := search(t, "paus putih")
+			items := resp.list()
+			if len(items) != 2 {
+				t.Fatalf("items = %d, want 2", len(items))
+			}
+			first, second := items[0].(map[string]any), items[1].(map[string]any)
+			if first["id"] != inTitle["id"] || second["id"] != inDescription["id"] {
+				t.Errorf("order = %v, %v, want title match %v first", first["id"], second["id"], inTitle["id"])
+			}
+			if first["score"].(float64) <= second["score"].(float64) {
+				t.Errorf("scores = %v, %v, want descending", first["score"], second["score"])
+			}
+		})
+
+		t.Run("highlights matched words", func(t *testing.T) {
+			item := search(t, "paus").list()[1].(map[string]any)
+			highlights, _ := item["highlights"].(map[string]any)
+			want := "Kisah perjalanan ke laut selatan bersama seekor <mark>paus</mark> putih yang legendaris."
+			if highlights["description"] != want {
+				t.Errorf("description highlight = %v, want %q", highlights["description"], want)
+			}
+			if _, ok := highlights["title"]; ok {
+				t.Errorf("title highlighted without match: %v", highlights["title"])
+			}
+		})
+
+		for _, tt := range []struct {
+			name  string
+			query string
+			want  int
+		}{
+			{"publisher", "bentang", 1},
+			{"prefix on last word", "pramoedya anan", 1},
+			{"diacritics ignored", "Pramoédya", 1},
+			{"all words must match", "paus pelangi", 0},
+			{"no match", "zzz", 0},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := len(search(t, tt.query).list()); got != tt.want {
+					t.Errorf("items = %d, want %d", got, tt.want)
+				}
+			})
+		}
+
+		t.Run("one letter prefix sorted and paged", func(t *testing.T) {
+			resp := doRequest(t, app, http.MethodGet, "/api/books?search=p&sort=title&limit=2&page=2", nil, "")
+			expectStatus(t, resp, http.StatusOK)
+			if resp.meta()["total"] != float64(4) {
+				t.Errorf("total = %v, want 4", resp.meta()["total"])
+			}
+			var titles []string
+			for _, item := range resp.list() {
+				titles = append(titles, item.(map[string]any)["title"].(string))
+			}
+			if want := []string{"Laskar Pelangi", "Paus Putih"}; fmt.Sprint(titles) != fmt.Sprint(want) {
+				t.Errorf("titles = %v, want %v", titles, want)
+			}
+		})
+
+		t.Run("short number is not an ISBN search", func(t *testing.T) {
+			titled := createBook(t, app, withField(withField(bookPayload(5), "title", "1984"), "author", "George Orwell"))
+			byISBN := createBook(t, app, withField(bookPayload(6), "isbn", "978-1-98400-001-9"))
+
+			items := search(t, "1984").list()
+			if len(items) != 2 || items[0].(map[string]any)["id"] != titled["id"] {
+				t.Errorf("search 1984 = %v, want title match %v first of 2", items, titled["id"])
+			}
+
+			items = search(t, "978-1-98400-001-9").list()
+			if len(items) == 0 || items[0].(map[string]any)["id"] != byISBN["id"] {
+				t.Errorf("search full ISBN = %v, want %v first", items, byISBN["id"])
+			}
+		})
+
+		t.Run("update reindexes book", func(t *testing.T) {
+			resp := doWithIfMatch(t, app, http.MethodPatch, bookPath(inTitle, ""), map[string]any{"title": "Moby Dick"}, admin)
+			expectStatus(t, resp, http.StatusOK)
+
+			if got := len(search(t, "moby").list()); got != 1 {
+				t.Errorf("search new title items = %d, want 1", got)
+			}
+			if got := len(search(t, "putih").list()); got != 1 {
+				t.Errorf("search old title items = %d, want 1 (description only)", got)
+			}
+		})
+
+		t.Run("deleted books are not found", func(t *testing.T) {
+			resp := doWithIfMatch(t, app, http.MethodDelete, bookPath(inDescription, ""), nil, admin)
+			expectStatus(t, resp, http.StatusNoContent)
+
+			if got := len(search(t, "perjalanan").list()); got != 0 {
+				t.Errorf("items = %d, want 0", got)
+			}
+		})
+	})
+}
+
+func TestBookFacets(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		for i, book := range []struct {
+			category, publisher, publishedAt string
+		}{
+			{"Fiction", "Gramedia", "1925-04-10T00:00:00Z"},
+			{"Fiction", "Gramedia", "1929-01-01T00:00:00Z"},
+			{"Fiction", "Mizan", "1960-07-11T00:00:00Z"},
+			{"Science", "Mizan", "1961-03-02T00:00:00Z"},
+			{"Science", "", ""},
+		} {
+			payload := withField(withField(bookPayload(i+1), "category", book.category), "publisher", book.publisher)
+			if book.publishedAt != "" {
+				payload["publisher_at"] = book.publishedAt
+			}
+			createBook(t, app, payload)
+		}
+
+		facetsOf := func(t *testing.T, query string) map[string]any {
+			t.Helper()
+			resp := doRequest(t, app, http.MethodGet, "/api/books"+query, nil, "")
+			expectStatus(t, resp, http.StatusOK)
+			facets, _ := resp.Body["facets"].(map[string]any)
+			return facets
+		}
+		counts := func(facet any) map[string]float64 {
+			result := map[string]float64{}
+			for _, item := range facet.([]any) {
+				count := item.(map[string]any)
+				result[count["value"].(string)] = count["count"].(float64)
+			}
+			return result
+		}
+
+		t.Run("omitted by default", func(t *testing.T) {
+			if facets := facetsOf(t, ""); facets != nil {
+				t.Errorf("facets = %v, want none", facets)
+			}
+		})
+
+		t.Run("all facets over every page", func(t *testing.T) {
+			facets := facetsOf(t, "?facets=all&limit=2")
+			if len(facets) != len(repositories.FacetNames) {
+				t.Fatalf("facets = %v, want %d facets", facets, len(repositories.FacetNames))
+			}
+
+			tests := map[string]map[string]float64{
+				"category":  {"Fiction": 3, "Science": 2},
+				"status":    {"available": 5},
+				"publisher": {"Gramedia": 2, "Mizan": 2},
+				"decade":    {"1920s": 2, "1960s": 2},
+			}
+			for name, want := range tests {
+				got := counts(facets[name])
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("facet %s = %v, want %v", name, got, want)
+				}
+			}
+
+			first := facets["category"].([]any)[0].(map[string]any)
+			if first["value"] != "Fiction" {
+				t.Errorf("first category = %v, want the largest count first", first["value"])
+			}
+		})
+
+		t.Run("follows filters", func(t *testing.T) {
+			facets := facetsOf(t, "?facets=decade,publisher&category=Science")
+			if got := counts(facets["decade"]); fmt.Sprint(got) != fmt.Sprint(map[string]float64{"1960s": 1}) {
+				t.Errorf("decade = %v, want only 1960s", got)
+			}
+			if _, ok := facets["category"]; ok {
+				t.Error("category facet returned without being requested")
+			}
+		})
+
+		t.Run("follows search", func(t *testing.T) {
+			facets := facetsOf(t, "?facets=category&search=gramedia")
+			if got := counts(facets["category"]); fmt.Sprint(got) != fmt.Sprint(map[string]float64{"Fiction": 2}) {
+				t.Errorf("category = %v, want Fiction 2", got)
+			}
+		})
+
+		t.Run("unknown facet", func(t *testing.T) {
+			resp := doRequest(t, app, http.MethodGet, "/api/books?facets=category,isbn", nil, "")
+			expectError(t, resp, http.StatusBadRequest, "invalid_facet")
+		})
+	})
+}
+
+func TestBookSortAndFilters(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		for i, book := range []map[string]any{
+			{"title": "Cahaya", "author": "Ani", "publisher": "Gramedia", "total_pages": 100, "publisher_at": "1990-05-01T00:00:00Z", "category": "Fiction"},
+			{"title": "Angin", "author": "Budi", "publisher": "Mizan", "total_pages": 300, "publisher_at": "2005-01-01T00:00:00Z", "category": "Science"},
+			{"title": "Bulan", "author": "ani", "publisher": "gramedia", "total_pages": 200, "publisher_at": "1990-05-01T00:00:00Z", "category": "History"},
+			{"title": "Dedaunan", "author": "Citra", "publisher": "Mizan", "total_pages": 50, "publisher_at": "2020-12-31T00:00:00Z", "category": "Fiction", "status": "maintenance"},
+		} {
+			book["isbn"] = isbn13(i + 1)
+			createBook(t, app, book)
+		}
+
+		tests := []struct {
+			name  string
+			query string
+			want  []string
+		}{
+			{"default newest first", "", []string{"Dedaunan", "Bulan", "Angin", "Cahaya"}},
+			{"sort ascending", "sort=title", []string{"Angin", "Bulan", "Cahaya", "Dedaunan"}},
+			{"sort descending then ascending", "sort=-publisher_at,title", []string{"Dedaunan", "Angin", "Bulan", "Cahaya"}},
+			{"sort on tie", "sort=publisher_at,-total_pages", []string{"Bulan", "Cahaya", "Angin", "Dedaunan"}},
+			{"categories", "category=Fiction,History&sort=title", []string{"Bulan", "Cahaya", "Dedaunan"}},
+			{"statuses", "status=maintenance,borrowed", []string{"Dedaunan"}},
+			{"author ignores case", "author=ANI&sort=title", []string{"Bulan", "Cahaya"}},
+			{"publisher", "publisher=mizan&sort=title", []string{"Angin", "Dedaunan"}},
+			{"pages range", "min_pages=100&max_pages=200&sort=title", []string{"Bulan", "Cahaya"}},
+			{"published range inclusive", "published_from=1990-05-01&published_to=2005-01-01&sort=title", []string{"Angin", "Bulan", "Cahaya"}},
+			{"search with sort", "search=mizan&sort=-title", []string{"Dedaunan", "Angin"}},
+			{"created since past", "created_since=2000-01-01T00:00:00Z&sort=title", []string{"Angin", "Bulan", "Cahaya", "Dedaunan"}},
+			{"created since future", "created_since=2999-01-01T00:00:00%2B07:00", []string{}},
+			{"updated since future", "updated_since=2999-01-01T00:00:00Z", []string{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodGet, "/api/books?"+tt.query, nil, "")
+				expectStatus(t, resp, http.StatusOK)
+
+				titles := []string{}
+				for _, item := range resp.list() {
+					titles = append(titles, item.(map[string]any)["title"].(string))
+				}
+				if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+					t.Errorf("titles = %v, want %v", titles, tt.want)
+				}
+			})
+		}
+
+		for _, tt := range []struct {
+			query string
+			code  string
+		}{
+			{"sort=isbn", "invalid_sort"},
+			{"sort=title,-title", "invalid_sort"},
+			{"min_pages=abc", "invalid_min_pages"},
+			{"max_pages=0", "invalid_max_pages"},
+			{"published_from=2020", "invalid_published_from"},
+			{"updated_since=2024-01-01", "invalid_updated_since"},
+		} {
+			t.Run("invalid "+tt.query, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodGet, "/api/books?"+tt.query, nil, "")
+				expectError(t, resp, http.StatusBadRequest, tt.code)
+			})
+		}
+	})
+}
+
+func TestBookCursorPagination(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		for i := 1; i <= 25; i++ {
+			payload := bookPayload(i)
+			payload["title"] = fmt.Sprintf("Book %02d", (i*7)%25)
+			payload["total_pages"] = 100 + i%3
+			createBook(t, app, payload)
+		}
+
+		ids := func(resp apiResponse) []any {
+			var result []any
+			for _, item := range resp.list() {
+				result = append(result, item.(map[string]any)["id"])
+			}
+			return result
+		}
+		cursorOf := func(resp apiResponse, key string) string {
+			cursor, _ := resp.meta()[key].(string)
+			return cursor
+		}
+
+		for _, sort := range []string{"", "title", "-total_pages,title", "total_pages,-created_at"} {
+			t.Run("walk sort="+sort, func(t *testing.T) {
+				offsetResp := doRequest(t, app, http.MethodGet, "/api/books?limit=100&sort="+sort, nil, "")
+				expectStatus(t, offsetResp, http.StatusOK)
+				want := ids(offsetResp)
+
+				var pages []apiResponse
+				var got []any
+				path := "/api/books?pagination=cursor&limit=10&sort=" + sort
+				for path != "" {
+					resp := doRequest(t, app, http.MethodGet, path, nil, "")
+					expectStatus(t, resp, http.StatusOK)
+					if _, ok := resp.meta()["total"]; ok {
+						t.Fatalf("cursor meta has total: %v", resp.meta())
+					}
+					pages = append(pages, resp)
+					got = append(got, ids(resp)...)
+
+					path = ""
+					if next := cursorOf(resp, "next_cursor"); next != "" {
+						path = "/api/books?limit=10&sort=" + sort + "&cursor=" + next
+					}
+				}
+				if fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Fatalf("cursor order = %v, want %v", got, want)
+				}
+				if len(pages) != 3 || cursorOf(pages[0], "prev_cursor") != "" {
+					t.Fatalf("pages = %d, first prev_cursor = %q; want 3 pages and no prev on first", len(pages), cursorOf(pages[0], "prev_cursor"))
+				}
+
+				// Mundur dari halaman terakhir harus menghasilkan halaman yang sama
+				prev := cursorOf(pages[2], "prev_cursor")
+				for i := 1; i >= 0; i-- {
+					resp := doRequest(t, app, http.MethodGet, "/api/books?limit=10&sort="+sort+"&cursor="+prev, nil, "")
+					expectStatus(t, resp, http.StatusOK)
+					if fmt.Sprint(ids(resp)) != fmt.Sprint(ids(pages[i])) {
+						t.Errorf("prev page %d = %v, want %v", i+1, ids(resp), ids(pages[i]))
+					}
+					if cursorOf(resp, "next_cursor") == "" {
+						t.Errorf("prev page %d has no next_cursor", i+1)
+					}
+					prev = cursorOf(resp, "prev_cursor")
+				}
+				if prev != "" {
+					t.Errorf("first page reached backwards has prev_cursor %q", prev)
+				}
+			})
+		}
+
+		t.Run("stable when books are added between pages", func(t *testing.T) {
+			first := doRequest(t, app, http.MethodGet, "/api/books?pagination=cursor&limit=10", nil, "")
+			createBook(t, app, bookPayload(26))
+
+			second := doRequest(t, app, http.MethodGet, "/api/books?limit=10&cursor="+cursorOf(first, "next_cursor"), nil, "")
+			expectStatus(t, second, http.StatusOK)
+			last := ids(first)[9].(float64)
+			if next := ids(second)[0].(float64); next != last-1 {
+				t.Errorf("second page starts at %v, want %v", next, last-1)
+			}
+		})
+
+		t.Run("limit is capped", func(t *testing.T) {
+			for _, path := range []string{"/api/books?limit=1000", "/api/books?pagination=cursor&limit=1000"} {
+				resp := doRequest(t, app, http.MethodGet, path, nil, "")
+				expectStatus(t, resp, http.StatusOK)
+				if resp.meta()["limit"] != float64(100) {
+					t.Errorf("%s meta.limit = %v, want 100", path, resp.meta()["limit"])
+				}
+			}
+		})
+
+		t.Run("invalid cursors", func(t *testing.T) {
+			first := doRequest(t, app, http.MethodGet, "/api/books?pagination=cursor&limit=5&sort=title", nil, "")
+			next := cursorOf(first, "next_cursor")
+
+			for _, tt := range []struct {
+				query string
+				code  string
+			}{
+				{"cursor=not-a-cursor", "invalid_cursor"},
+				{"cursor=" + next, "invalid_cursor"},
+				{"cursor=" + next + "&sort=-title", "invalid_cursor"},
+				{"cursor=" + next + "&sort=title&search=book", "cursor_with_search"},
+			} {
+				resp := doRequest(t, app, http.MethodGet, "/api/books?"+tt.query, nil, "")
+				expectError(t, resp, http.StatusBadRequest, tt.code)
+			}
+		})
+	})
+}
+
+func TestFuzzySearch(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		createBook(t, app, withField(withField(bookPayload(1), "title", "Nineteen Eighty-Four"), "author", "George Orwell"))
+		createBook(t, app, withField(withField(bookPayload(2), "title", "Animal Farm"), "author", "George Orwell"))
+		createBook(t, app, withField(withField(bookPayload(3), "title", "The Great Gatsby"), "author", "F. Scott Fitzgerald"))
+
+		for _, tt := range []struct {
+			name       string
+			query      string
+			want       int
+			didYouMean string
+		}{
+			{"incomplete last word is a prefix", "Orwel", 2, ""},
+			{"missing letter", "orwll", 2, "orwell"},
+			{"two typos in long word", "Fitzgrld", 1, "fitzgerald"},
+			{"transposed letters", "gatbsy", 1, "gatsby"},
+			{"transposed first letters", "rowell", 2, "orwell"},
+			{"typo in first letter", "prwell", 0, ""},
+			{"typo with exact word", "animal frm", 0, ""},
+			{"typo among exact words", "george orwel farm", 1, "george orwell farm"},
+			{"exact words are not corrected", "orwell", 2, ""},
+			{"short words are not corrected", "fam", 0, ""},
+			{"too many typos", "orwxyz", 0, ""},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodGet, "/api/books?search="+url.QueryEscape(tt.query), nil, "")
+				expectStatus(t, resp, http.StatusOK)
+				if got := len(resp.list()); got != tt.want {
+					t.Errorf("items = %d, want %d", got, tt.want)
+				}
+				if got, _ := resp.Body["did_you_mean"].(string); got != tt.didYouMean {
+					t.Errorf("did_you_mean = %q, want %q", got, tt.didYouMean)
+				}
+			})
+		}
+
+		t.Run("highlights corrected word", func(t *testing.T) {
+			resp := doRequest(t, app, http.MethodGet, "/api/books?search=fitzgerld", nil, "")
+			item := resp.list()[0].(map[string]any)
+			highlights, _ := item["highlights"].(map[string]any)
+			if highlights["author"] != "F. Scott <mark>Fitzgerald</mark>" {
+				t.Errorf("author highlight = %v", highlights["author"])
+			}
+		})
+	})
+}
+
+func TestSuggestBooks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		createBook(t, app, withField(withField(bookPayload(1), "title", "Nineteen Eighty-Four"), "author", "George Orwell"))
+		farm := createBook(t, app, withField(withField(bookPayload(2), "title", "Animal Farm"), "author", "George Orwell"))
+		createBook(t, app, withField(withField(bookPayload(3), "title", "Georgia on My Mind"), "author", "Hoagy Carmichael"))
+		deleted := createBook(t, app, withField(withField(bookPayload(4), "title", "George's Marvellous Medicine"), "author", "Roald Dahl"))
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(deleted, ""), nil, admin), http.StatusNoContent)
+
+		suggest := func(t *testing.T, query string) []map[string]any {
+			t.Helper()
+			resp := doRequest(t, app, http.MethodGet, "/api/books/suggest?"+query, nil, "")
+			expectStatus(t, resp, http.StatusOK)
+			var result []map[string]any
+			for _, item := range resp.Body["data"].([]any) {
+				result = append(result, item.(map[string]any))
+			}
+			return result
+		}
+
+		t.Run("prefix matches titles and authors", func(t *testing.T) {
+			got := suggest(t, "q=geor")
+			texts := map[string]map[string]any{}
+			for _, suggestion := range got {
+				texts[suggestion["field"].(string)+":"+suggestion["text"].(string)] = suggestion
+			}
+			if len(got) != 2 {
+				t.Fatalf("suggestions = %v, want George Orwell and Georgia on My Mind", got)
+			}
+			author := texts["author:George Orwell"]
+			if author == nil || author["book_count"] != float64(2) || author["highlight"] != "<mark>George</mark> Orwell" {
+				t.Errorf("author suggestion = %v, want George Orwell with 2 books", author)
+			}
+			if texts["title:Georgia on My Mind"] == nil {
+				t.Errorf("suggestions = %v, missing title Georgia on My Mind", got)
+			}
+		})
+
+		t.Run("tolerates typos", func(t *testing.T) {
+			got := suggest(t, "q=animl%20farm")
+			if len(got) != 1 || got[0]["text"] != "Animal Farm" || got[0]["book_id"] != farm["id"] {
+				t.Errorf("suggestions = %v, want title Animal Farm", got)
+			}
+		})
+
+		t.Run("limit", func(t *testing.T) {
+			if got := suggest(t, "q=geor&limit=1"); len(got) != 1 {
+				t.Errorf("suggestions = %d, want 1", len(got))
+			}
+		})
+
+		t.Run("no match", func(t *testing.T) {
+			if got := suggest(t, "q=zzzz"); len(got) != 0 {
+				t.Errorf("suggestions = %v, want none", got)
+			}
+		})
+
+		for _, query := range []string{"", "q=%20", "q=geor&limit=0", "q=geor&limit=21"} {
+			t.Run("invalid "+query, func(t *testing.T) {
+				resp := doRequest(t, app, http.MethodGet, "/api/books/suggest?"+query, nil, "")
+				expectStatus(t, resp, http.StatusBadRequest)
+			})
+		}
+	})
+}
+
+func TestGetBookByID(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		book := createBook(t, app, bookPayload(1))
+
+		resp := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["title"] != "Book 01" {
+			t.Errorf("title = %v, want Book 01", resp.data()["title"])
+		}
+		if resp.data()["total_copies"] != float64(1) {
+			t.Errorf("total_copies = %v, want 1", resp.data()["total_copies"])
+		}
+
+		for _, id := range []string{"abc", "-1", "1.5"} {
+			resp := doRequest(t, app, http.MethodGet, "/api/books/"+id, nil, "")
+			expectError(t, resp, http.StatusBadRequest, "invalid_id")
+		}
+
+		expectError(t, doRequest(t, app, http.MethodGet, "/api/books/999", nil, ""), http.StatusNotFound, "book_not_found")
+
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, tokenFor(t, models.RoleAdmin)), http.StatusNoContent)
+		expectError(t, doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, ""), http.StatusNotFound, "book_not_found")
+	})
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		expectError(t, doRequest(t, app, http.MethodGet, "/api/unknown", nil, ""), http.StatusNotFound, "route_not_found")
+
+		t.Run("request id from header", func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/books/999", nil)
+			req.Header.Set(fiber.HeaderXRequestID, "req-test-1")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if got := resp.Header.Get(fiber.HeaderXRequestID); got != "req-test-1" {
+				t.Errorf("X-Request-ID = %q, want req-test-1", got)
+			}
+			var body struct {
+				RequestID string `json:"request_id"`
+				Error     struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.RequestID != "req-test-1" || body.Error.Code != "book_not_found" || body.Error.Message == "" {
+				t.Errorf("body = %+v", body)
+			}
+		})
+	})
+}
+
+func TestUpdateBook(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		book := createBook(t, app, withField(bookPayload(1), "description", "First edition"))
+		other := createBook(t, app, bookPayload(2))
+
+		payload := withField(bookPayload(1), "title", "Replaced")
+		resp := doWithIfMatch(t, app, http.MethodPut, bookPath(book, ""), payload, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["title"] != "Replaced" || resp.data()["description"] != "" {
+			t.Errorf("replaced book = %v, want new title and cleared description", resp.data())
+		}
+
+		resp = doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+		if resp.data()["title"] != "Replaced" {
+			t.Errorf("stored title = %v, want Replaced", resp.data()["title"])
+		}
+
+		tests := []struct {
+			name  string
+			path  string
+			body  map[string]any
+			token string
+			want  int
+		}{
+			{"partial body", bookPath(book, ""), map[string]any{"title": "x"}, admin, http.StatusUnprocessableEntity},
+			{"isbn conflict", bookPath(book, ""), withField(bookPayload(1), "isbn", other["isbn"]), admin, http.StatusConflict},
+			{"same isbn is not a conflict", bookPath(book, ""), bookPayload(1), admin, http.StatusOK},
+			{"maintenance status", bookPath(book, ""), withField(bookPayload(1), "status", "maintenance"), admin, http.StatusOK},
+			{"borrowed status", bookPath(book, ""), withField(bookPayload(1), "status", "borrowed"), admin, http.StatusBadRequest},
+			{"invalid isbn", bookPath(book, ""), withField(bookPayload(1), "isbn", "978-0000000000"), admin, http.StatusUnprocessableEntity},
+			{"unknown status", bookPath(book, ""), withField(bookPayload(1), "status", "lost"), admin, http.StatusUnprocessableEntity},
+			{"invalid id", "/api/books/abc", bookPayload(1), admin, http.StatusBadRequest},
+			{"not found", "/api/books/999", bookPayload(1), admin, http.StatusNotFound},
+			{"without token", bookPath(book, ""), bookPayload(1), "", http.StatusUnauthorized},
+			{"member token", bookPath(book, ""), bookPayload(1), tokenFor(t, models.RoleUser), http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp := doWithIfMatch(t, app, http.MethodPut, tt.path, tt.body, tt.token)
+				expectStatus(t, resp, tt.want)
+			})
+		}
+
+		t.Run("status and fields in one version", func(t *testing.T) {
+			before := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "").data()["version"].(float64)
+			payload := withField(withField(bookPayload(1), "title", "Reshelved"), "status", "available")
+			resp := doWithIfMatch(t, app, http.MethodPut, bookPath(book, ""), payload, admin)
+			expectStatus(t, resp, http.StatusOK)
+			if resp.data()["title"] != "Reshelved" || resp.data()["status"] != "available" || resp.data()["version"] != before+1 {
+				t.Errorf("book = %v, want new title, available, version %v", resp.data(), before+1)
+			}
+			if etag := resp.Header.Get("ETag"); etag != fmt.Sprintf(`"%v"`, before+1) {
+				t.Errorf("ETag = %s, want version %v", etag, before+1)
+			}
+		})
+	})
+}
+
+func TestUpdateBookIsAtomic(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	admin := tokenFor(t, models.RoleAdmin)
+	book := createBook(t, app, bookPayload(1))
+
+	// Status eksemplar yang gagal disimpan harus membatalkan perubahan field lain juga
+	err := db.Exec(`CREATE TRIGGER fail_copy_status BEFORE UPDATE OF status ON book_copies
+		BEGIN SELECT RAISE(ABORT, 'copy status rejected'); END`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := withField(withField(bookPayload(1), "title", "Half Applied"), "status", "maintenance")
+	expectStatus(t, doWithIfMatch(t, app, http.MethodPut, bookPath(book, ""), payload, admin), http.StatusInternalServerError)
+
+	resp := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+	if resp.data()["title"] != book["title"] || resp.data()["version"] != book["version"] {
+		t.Errorf("book after failed update = %v, want title %v version %v", resp.data(), book["title"], book["version"])
+	}
+}
+
+func TestPatchBook(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		payload := bookPayload(1)
+		payload["description"] = "First edition"
+		payload["total_pages"] = 120
+		book := createBook(t, app, payload)
+
+		resp := doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), map[string]any{"title": "Patched"}, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["title"] != "Patched" || resp.data()["author"] != "Author 01" || resp.data()["description"] != "First edition" {
+			t.Errorf("patched book = %v, want only title changed", resp.data())
+		}
+
+		patch := map[string]any{"description": nil, "category": "", "total_pages": 0}
+		resp = doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), patch, admin)
+		expectStatus(t, resp, http.StatusOK)
+		data := resp.data()
+		if data["description"] != "" || data["category"] != "" || data["total_pages"] != float64(0) || data["title"] != "Patched" {
+			t.Errorf("patched book = %v, want description, category and total_pages cleared", data)
+		}
+
+		resp = doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+		if resp.data()["description"] != "" || resp.data()["total_pages"] != float64(0) {
+			t.Errorf("stored book = %v, want cleared fields", resp.data())
+		}
+
+		t.Run("null required field", func(t *testing.T) {
+			resp := doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), map[string]any{"title": nil}, admin)
+			expectError(t, resp, http.StatusUnprocessableEntity, "validation_failed")
+		})
+
+		t.Run("status", func(t *testing.T) {
+			resp := doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), map[string]any{"status": "maintenance"}, admin)
+			expectStatus(t, resp, http.StatusOK)
+			if resp.data()["status"] != models.BookStatusMaintenance {
+				t.Errorf("status = %v, want maintenance", resp.data()["status"])
+			}
+
+			// Patch tanpa status tidak mengembalikan eksemplar ke rak
+			resp = doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), map[string]any{"publisher": "Scribner"}, admin)
+			expectStatus(t, resp, http.StatusOK)
+			if resp.data()["status"] != models.BookStatusMaintenance {
+				t.Errorf("status after unrelated patch = %v, want maintenance", resp.data()["status"])
+			}
+		})
+
+		contentTypes := []struct {
+			contentType string
+			want        int
+		}{
+			{"application/merge-patch+json", http.StatusOK},
+			{"application/merge-patch+json; charset=utf-8", http.StatusOK},
+			{"text/plain", http.StatusUnsupportedMediaType},
+		}
+		for _, tt := range contentTypes {
+			t.Run(tt.contentType, func(t *testing.T) {
+				etag := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "").Header.Get("ETag")
+				req := httptest.NewRequest(http.MethodPatch, bookPath(book, ""), strings.NewReader(`{"publisher":"Penguin"}`))
+				req.Header.Set("Content-Type", tt.contentType)
+				req.Header.Set("If-Match", etag)
+				req.Header.Set("Authorization", "Bearer "+admin)
+				resp, err := app.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.want {
+					t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+				}
+			})
+		}
+
+		t.Run("invalid body", func(t *testing.T) {
+			resp := doWithIfMatch(t, app, http.MethodPatch, bookPath(book, ""), map[string]any{"total_pages": "many"}, admin)
+			expectError(t, resp, http.StatusBadRequest, "invalid_body")
+		})
+	})
+}
+
+func TestBookETag(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		book := createBook(t, app, bookPayload(1))
+		path := bookPath(book, "")
+		conditional := func(method, header, value string, body any) apiResponse {
+			return doRequestWithHeaders(t, app, method, path, body, admin, map[string]string{header: value})
+		}
+
+		resp := doRequest(t, app, http.MethodGet, path, nil, "")
+		etag := resp.Header.Get("ETag")
+		if want := fmt.Sprintf(`"%v"`, resp.data()["version"]); etag != want {
+			t.Fatalf("ETag = %q, want %q from version", etag, want)
+		}
+
+		t.Run("if-none-match", func(t *testing.T) {
+			for _, value := range []string{etag, "W/" + etag, `"0", ` + etag, "*"} {
+				resp := conditional(http.MethodGet, "If-None-Match", value, nil)
+				if resp.Status != http.StatusNotModified || resp.Body != nil {
+					t.Errorf("If-None-Match %s: status = %d body %v, want 304 without body", value, resp.Status, resp.Body)
+				}
+			}
+			expectStatus(t, conditional(http.MethodGet, "If-None-Match", `"0"`, nil), http.StatusOK)
+		})
+
+		t.Run("if-match required", func(t *testing.T) {
+			expectError(t, doRequest(t, app, http.MethodPut, path, bookPayload(1), admin), http.StatusPreconditionRequired, "if_match_required")
+			expectError(t, doRequest(t, app, http.MethodPatch, path, map[string]any{"title": "x"}, admin), http.StatusPreconditionRequired, "if_match_required")
+			expectError(t, doRequest(t, app, http.MethodDelete, path, nil, admin), http.StatusPreconditionRequired, "if_match_required")
+		})
+
+		resp = conditional(http.MethodPatch, "If-Match", etag, map[string]any{"title": "First edit"})
+		expectStatus(t, resp, http.StatusOK)
+		newETag := resp.Header.Get("ETag")
+		if newETag == "" || newETag == etag {
+			t.Fatalf("ETag after update = %q, want a new tag (old %q)", newETag, etag)
+		}
+
+		t.Run("stale if-match", func(t *testing.T) {
+			expectError(t, conditional(http.MethodPut, "If-Match", etag, bookPayload(1)), http.StatusPreconditionFailed, "book_modified")
+			expectError(t, conditional(http.MethodPatch, "If-Match", etag, map[string]any{"title": "Second edit"}), http.StatusPreconditionFailed, "book_modified")
+			expectError(t, conditional(http.MethodPatch, "If-Match", "W/"+newETag, map[string]any{"title": "Second edit"}), http.StatusPreconditionFailed, "book_modified")
+			expectError(t, conditional(http.MethodDelete, "If-Match", etag, nil), http.StatusPreconditionFailed, "book_modified")
+
+			resp := doRequest(t, app, http.MethodGet, path, nil, "")
+			if resp.data()["title"] != "First edit" {
+				t.Errorf("title = %v, want the first edit kept", resp.data()["title"])
+			}
+		})
+
+		t.Run("status change invalidates etag", func(t *testing.T) {
+			expectStatus(t, patchStatus(t, app, book, "maintenance", admin), http.StatusOK)
+			expectError(t, conditional(http.MethodPatch, "If-Match", newETag, map[string]any{"title": "x"}), http.StatusPreconditionFailed, "book_modified")
+		})
+
+		expectStatus(t, conditional(http.MethodDelete, "If-Match", "*", nil), http.StatusNoContent)
+	})
+}
+
+func TestUpdateBookStatus(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		book := createBook(t, app, withField(bookPayload(1), "copies", 2))
+
+		resp := patchStatus(t, app, book, "maintenance", admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.Body["status"] != models.BookStatusMaintenance {
+			t.Errorf("status = %v, want maintenance", resp.Body["status"])
+		}
+
+		resp = doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+		if resp.data()["status"] != models.BookStatusMaintenance || resp.data()["available_copies"] != float64(0) {
+			t.Errorf("book after maintenance = %v", resp.data())
+		}
+
+		resp = doRequest(t, app, http.MethodGet, "/api/books?status=maintenance", nil, "")
+		if resp.meta()["total"] != float64(1) {
+			t.Errorf("status filter total = %v, want 1", resp.meta()["total"])
+		}
+
+		resp = patchStatus(t, app, book, "available", admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.Body["status"] != models.BookStatusAvailable {
+			t.Errorf("status = %v, want available", resp.Body["status"])
+		}
+
+		tests := []struct {
+			name  string
+			path  string
+			body  map[string]any
+			token string
+			want  int
+		}{
+			{"borrowed only through loans", bookPath(book, "/status"), map[string]any{"status": "borrowed"}, admin, http.StatusBadRequest},
+			{"unknown status", bookPath(book, "/status"), map[string]any{"status": "lost"}, admin, http.StatusUnprocessableEntity},
+			{"empty status", bookPath(book, "/status"), map[string]any{}, admin, http.StatusUnprocessableEntity},
+			{"invalid id", "/api/books/abc/status", map[string]any{"status": "available"}, admin, http.StatusBadRequest},
+			{"not found", "/api/books/999/status", map[string]any{"status": "available"}, admin, http.StatusNotFound},
+			{"member token", bookPath(book, "/status"), map[string]any{"status": "available"}, tokenFor(t, models.RoleUser), http.StatusForbidden},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				etag := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "").Header.Get("ETag")
+				resp := doRequestWithHeaders(t, app, http.MethodPatch, tt.path, tt.body, tt.token, map[string]string{"If-Match": etag})
+				expectStatus(t, resp, tt.want)
+			})
+		}
+
+		t.Run("if-match", func(t *testing.T) {
+			resp := doRequest(t, app, http.MethodPatch, bookPath(book, "/status"), map[string]any{"status": "maintenance"}, admin)
+			expectError(t, resp, http.StatusPreconditionRequired, "if_match_required")
+
+			resp = doRequestWithHeaders(t, app, http.MethodPatch, bookPath(book, "/status"), map[string]any{"status": "maintenance"}, admin, map[string]string{"If-Match": `"1"`})
+			expectError(t, resp, http.StatusPreconditionFailed, "book_modified")
+
+			resp = patchStatus(t, app, book, "maintenance", admin)
+			expectStatus(t, resp, http.StatusOK)
+			current := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+			if etag := resp.Header.Get("ETag"); etag == "" || etag != current.Header.Get("ETag") {
+				t.Errorf("ETag = %q, want current %q", etag, current.Header.Get("ETag"))
+			}
+		})
+	})
+}
+
+func TestUpdateBookStatusKeepsLostCopies(t *testing.T) {
+	app := newSQLiteApp(t)
+	admin := tokenFor(t, models.RoleAdmin)
+	book := createBook(t, app, withField(bookPayload(1), "copies", 2))
+
+	copies := doRequest(t, app, http.MethodGet, bookPath(book, "/copies"), nil, "").list()
+	lost, _ := copies[0].(map[string]any)
+	lostPath := fmt.Sprintf("/api/copies/%v/status", lost["id"])
+	expectStatus(t, doRequest(t, app, http.MethodPatch, lostPath, map[string]any{"status": "lost"}, admin), http.StatusOK)
+
+	expectStatus(t, patchStatus(t, app, book, "maintenance", admin), http.StatusOK)
+	expectStatus(t, patchStatus(t, app, book, "available", admin), http.StatusOK)
+
+	resp := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+	if resp.data()["available_copies"] != float64(1) {
+		t.Errorf("available_copies = %v, want 1", resp.data()["available_copies"])
+	}
+	for _, item := range doRequest(t, app, http.MethodGet, bookPath(book, "/copies"), nil, "").list() {
+		bookCopy, _ := item.(map[string]any)
+		if bookCopy["id"] == lost["id"] && bookCopy["status"] != models.CopyStatusLost {
+			t.Errorf("lost copy status = %v, want lost", bookCopy["status"])
+		}
+	}
+}
+
+func TestCopyCountsError(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	book := createBook(t, app, bookPayload(1))
+
+	// Jumlah eksemplar yang gagal dihitung tidak boleh tampil sebagai nol eksemplar
+	if err := db.Exec("ALTER TABLE book_copies RENAME TO book_copies_old").Error; err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, ""), http.StatusInternalServerError, "internal_error")
+}
+
+func TestDeleteRestoreAndPurgeBook(t *testing.T) {
+	forEachStore(t, func(t *testing.T, app *fiber.App) {
+		admin := tokenFor(t, models.RoleAdmin)
+		book := createBook(t, app, bookPayload(1))
+		createBook(t, app, bookPayload(2))
+
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, "/api/books/abc", nil, admin), http.StatusBadRequest)
+		expectError(t, doWithIfMatch(t, app, http.MethodDelete, "/api/books/999", nil, admin), http.StatusNotFound, "book_not_found")
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, ""), http.StatusUnauthorized)
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, tokenFor(t, models.RoleUser)), http.StatusForbidden)
+
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, admin), http.StatusNoContent)
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, admin), http.StatusNotFound)
+
+		resp := doRequest(t, app, http.MethodGet, "/api/books", nil, "")
+		if resp.meta()["total"] != float64(1) {
+			t.Errorf("total after delete = %v, want 1", resp.meta()["total"])
+		}
+
+		// ISBN buku di trash tetap terpakai sampai di-purge
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/books", bookPayload(1), admin), http.StatusConflict)
+
+		resp = doRequest(t, app, http.MethodGet, "/api/books/trash", nil, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if len(resp.list()) != 1 || resp.meta()["total"] != float64(1) {
+			t.Fatalf("trash = %v, meta %v", resp.list(), resp.meta())
+		}
+		if trashed := resp.list()[0].(map[string]any); trashed["id"] != book["id"] || trashed["deleted_at"] == nil {
+			t.Errorf("trashed book = %v", trashed)
+		}
+		expectStatus(t, doRequest(t, app, http.MethodGet, "/api/books/trash", nil, tokenFor(t, models.RoleUser)), http.StatusForbidden)
+
+		resp = doRequest(t, app, http.MethodPost, bookPath(book, "/restore"), nil, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["id"] != book["id"] {
+			t.Errorf("restored book = %v", resp.data())
+		}
+		expectStatus(t, doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, ""), http.StatusOK)
+		expectError(t, doRequest(t, app, http.MethodPost, bookPath(book, "/restore"), nil, admin), http.StatusNotFound, "book_not_in_trash")
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/books/abc/restore", nil, admin), http.StatusBadRequest)
+
+		expectStatus(t, doWithIfMatch(t, app, http.MethodDelete, bookPath(book, ""), nil, admin), http.StatusNoContent)
+
+		expectStatus(t, doRequest(t, app, http.MethodDelete, "/api/books/trash?older_than_days=-1", nil, admin), http.StatusBadRequest)
+
+		resp = doRequest(t, app, http.MethodDelete, "/api/books/trash", nil, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["purged"] != float64(0) {
+			t.Errorf("purged within retention = %v, want 0", resp.data()["purged"])
+		}
+
+		resp = doRequest(t, app, http.MethodDelete, "/api/books/trash?older_than_days=0", nil, admin)
+		expectStatus(t, resp, http.StatusOK)
+		if resp.data()["purged"] != float64(1) {
+			t.Errorf("purged = %v, want 1", resp.data()["purged"])
+		}
+
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/books", bookPayload(1), admin), http.StatusCreated)
+	})
+}
+
+func withField(payload map[string]any, key string, value any) map[string]any {
+	payload[key] = value
+	return payload
+}
+
+// forEachBookStore menjalankan test langsung ke setiap BookStore dengan satu book yang baru dibuat.
+// Handler selalu mengecek book lebih dulu, jadi aturan yang hanya terlihat saat request balapan dicek
+// di level store supaya MemoryBookStore tetap setara dengan BookRepository.
+func forEachBookStore(t *testing.T, test func(t *testing.T, store repositories.BookStore, book models.Book)) {
+	stores := map[string]func() repositories.BookStore{
+		"sqlite": func() repositories.BookStore { return repositories.NewBookRepository(newSQLiteDB(t)) },
+		"memory": func() repositories.BookStore { return repositories.NewMemoryBookStore() },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			book := models.Book{Title: "Book 01", Author: "Author 01", ISBN: isbn13(1), Category: "Fiction",
+				Copies: []models.BookCopy{{Status: models.CopyStatusAvailable}}}
+			if err := store.Create(&book); err != nil {
+				t.Fatal(err)
+			}
+			test(t, store, book)
+		})
+	}
+}
+
+func TestBookStoreTrashRules(t *testing.T) {
+	forEachBookStore(t, func(t *testing.T, store repositories.BookStore, book models.Book) {
+		if err := store.Restore(book.ID); !errors.Is(err, repositories.ErrBookNotInTrash) {
+			t.Errorf("restore live book error = %v, want book_not_in_trash", err)
+		}
+		if live, err := store.GetByID(book.ID); err != nil || live.Version != book.Version {
+			t.Errorf("version after rejected restore = %v (err %v), want %d", live, err, book.Version)
+		}
+
+		if err := store.Delete(book.ID, book.Version); err != nil {
+			t.Fatal(err)
+		}
+		book.Title = "Trashed"
+		if err := store.Update(&book, ""); !errors.Is(err, repositories.ErrBookNotFound) {
+			t.Errorf("update trashed book error = %v, want book_not_found", err)
+		}
+		if err := store.Restore(book.ID); err != nil {
+			t.Errorf("restore trashed book: %v", err)
+		}
+	})
+}
+
+func TestBookStoreStaleVersion(t *testing.T) {
+	forEachBookStore(t, func(t *testing.T, store repositories.BookStore, book models.Book) {
+		stale := book.Version
+		book.Title = "Edited"
+		if err := store.Update(&book, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		// Perubahan dengan version sebelum edit dijawab 412 seperti If-Match yang basi
+		err := store.Delete(book.ID, stale)
+		if !errors.Is(err, repositories.ErrBookModified) {
+			t.Errorf("delete with stale version error = %v, want book_modified", err)
+		}
+		staleBook := book
+		staleBook.Version = stale
+		if err := store.UpdateStatus(&staleBook, models.CopyStatusMaintenance); !errors.Is(err, repositories.ErrBookModified) {
+			t.Errorf("status change with stale version error = %v, want book_modified", err)
+		}
+		if current, _ := store.GetByID(book.ID); current.Status != models.BookStatusAvailable {
+			t.Errorf("status after stale change = %s, want available", current.Status)
+		}
+		if _, err := store.GetByID(book.ID); err != nil {
+			t.Errorf("book after stale delete: %v", err)
+		}
+		if err := store.Delete(book.ID, book.Version); err != nil {
+			t.Errorf("delete with current version: %v", err)
+		}
+	})
+}
+
+func TestRunShutsDownAndClosesDB(t *testing.T) {
+	dsn := config.SQLiteDSN(filepath.Join(t.TempDir(), "run.db"))
+	db, err := config.OpenDB(sqlite.Open(dsn), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+
+	app := New(Config{DB: db})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, app, "127.0.0.1:0", db, time.Second)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context was cancelled")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlDB.Ping(); err == nil {
+		t.Error("database still open after shutdown")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+
+	register := map[string]any{"name": "Member", "email": "member@test.local", "password": "secret123"}
+	expectStatus(t, doRequest(t, app, http.MethodPost, "/api/auth/register", register, ""), http.StatusCreated)
+
+	login := func(email, password string) apiResponse {
+		return doRequest(t, app, http.MethodPost, "/api/auth/login", map[string]any{"email": email, "password": password}, "")
+	}
+	resp := login("member@test.local", "secret123")
+	expectStatus(t, resp, http.StatusOK)
+	refreshToken, _ := resp.data()["refresh_token"].(string)
+	expectError(t, login("member@test.local", "wrong-password"), http.StatusUnauthorized, "invalid_credentials")
+	expectError(t, login("nobody@test.local", "secret123"), http.StatusUnauthorized, "invalid_credentials")
+
+	refresh := func() apiResponse {
+		return doRequest(t, app, http.MethodPost, "/api/auth/refresh", map[string]any{"refresh_token": refreshToken}, "")
+	}
+	expectStatus(t, refresh(), http.StatusOK)
+
+	// Database yang tidak bisa dipakai bukan kredensial atau refresh token yang salah
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+	expectError(t, login("member@test.local", "secret123"), http.StatusInternalServerError, "internal_error")
+	expectError(t, refresh(), http.StatusInternalServerError, "internal_error")
+}
+
+func TestUserAccessRevocation(t *testing.T) {
+	app := newSQLiteApp(t)
+	admin := tokenFor(t, models.RoleAdmin)
+	member := tokenFor(t, models.RoleUser)
+
+	// Admin kedua yang diturunkan kehilangan akses admin walaupun token lamanya masih berlaku
+	second := map[string]any{"name": "Second", "email": "second@test.local", "password": "secret123", "role": "admin"}
+	created := doRequest(t, app, http.MethodPost, "/api/users", second, admin)
+	expectStatus(t, created, http.StatusCreated)
+	login := doRequest(t, app, http.MethodPost, "/api/auth/login", map[string]any{"email": "second@test.local", "password": "secret123"}, "")
+	expectStatus(t, login, http.StatusOK)
+	secondToken, _ := login.data()["access_token"].(string)
+	expectStatus(t, doRequest(t, app, http.MethodGet, "/api/users", nil, secondToken), http.StatusOK)
+
+	secondPath := fmt.Sprintf("/api/users/%v", created.data()["id"])
+	expectStatus(t, doRequest(t, app, http.MethodPatch, secondPath+"/role", map[string]any{"role": "user"}, admin), http.StatusOK)
+	expectError(t, doRequest(t, app, http.MethodGet, "/api/users", nil, secondToken), http.StatusForbidden, "insufficient_role")
+
+	// Member yang dinonaktifkan atau dihapus langsung ditolak
+	memberPath := fmt.Sprintf("/api/users/%d", testUserIDs[models.RoleUser])
+	expectStatus(t, doRequest(t, app, http.MethodGet, "/api/loans", nil, member), http.StatusOK)
+	expectStatus(t, doRequest(t, app, http.MethodPatch, memberPath+"/active", map[string]any{"is_active": false}, admin), http.StatusOK)
+	expectError(t, doRequest(t, app, http.MethodGet, "/api/loans", nil, member), http.StatusForbidden, "account_inactive")
+
+	expectStatus(t, doRequest(t, app, http.MethodDelete, memberPath, nil, admin), http.StatusOK)
+	expectError(t, doRequest(t, app, http.MethodGet, "/api/loans", nil, member), http.StatusUnauthorized, "invalid_token")
+
+	// Email user yang sudah dihapus tetap terpakai karena unique index email
+	register := map[string]any{"name": "Again", "email": "user@test.local", "password": "secret123"}
+	expectError(t, doRequest(t, app, http.MethodPost, "/api/auth/register", register, ""), http.StatusConflict, "email_exists")
+}
+
+func TestCheckoutByCopy(t *testing.T) {
+	app := newSQLiteApp(t)
+	member := tokenFor(t, models.RoleUser)
+
+	// ID eksemplar sengaja berbeda dengan ID book-nya
+	createBook(t, app, withField(bookPayload(1), "copies", 1))
+	book := createBook(t, app, withField(bookPayload(2), "copies", 2))
+	copies := doRequest(t, app, http.MethodGet, bookPath(book, "/copies"), nil, "").list()
+	target, _ := copies[len(copies)-1].(map[string]any)
+
+	resp := doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"copy_id": target["id"]}, member)
+	expectStatus(t, resp, http.StatusCreated)
+	if resp.data()["book_id"] != book["id"] || resp.data()["copy_id"] != target["id"] {
+		t.Errorf("loan = %v, want book %v copy %v", resp.data(), book["id"], target["id"])
+	}
+
+	expectError(t, doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"copy_id": target["id"]}, member), http.StatusConflict, "book_not_available")
+}
+
+func TestRenewLoan(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	admin, member := tokenFor(t, models.RoleAdmin), tokenFor(t, models.RoleUser)
+
+	books := 0
+	checkout := func(t *testing.T) (loan map[string]any, renewPath string) {
+		t.Helper()
+		books++
+		book := createBook(t, app, withField(bookPayload(books), "copies", 1))
+		resp := doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"book_id": book["id"]}, member)
+		expectStatus(t, resp, http.StatusCreated)
+		return resp.data(), fmt.Sprintf("/api/loans/%v/renew", resp.data()["id"])
+	}
+	dueAt := func(t *testing.T, loan map[string]any) time.Time {
+		t.Helper()
+		due, err := time.Parse(time.RFC3339Nano, loan["due_at"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return due
+	}
+
+	t.Run("extends due date", func(t *testing.T) {
+		loan, renewPath := checkout(t)
+		resp := doRequest(t, app, http.MethodPost, renewPath, nil, member)
+		expectStatus(t, resp, http.StatusOK)
+
+		if resp.data()["renewal_count"] != float64(1) {
+			t.Errorf("renewal_count = %v, want 1", resp.data()["renewal_count"])
+		}
+		if got, want := dueAt(t, resp.data()), dueAt(t, loan).Add(config.Library.LoanPeriod); !got.Equal(want) {
+			t.Errorf("due_at = %v, want %v", got, want)
+		}
+		if renewals, _ := resp.data()["renewals"].([]any); len(renewals) != 1 {
+			t.Errorf("renewals = %v, want 1 entry", resp.data()["renewals"])
+		}
+	})
+
+	t.Run("renewal limit", func(t *testing.T) {
+		_, renewPath := checkout(t)
+		for range config.Library.MaxRenewals {
+			expectStatus(t, doRequest(t, app, http.MethodPost, renewPath, nil, member), http.StatusOK)
+		}
+		expectError(t, doRequest(t, app, http.MethodPost, renewPath, nil, member), http.StatusConflict, "renewal_limit_reached")
+	})
+
+	t.Run("overdue loan", func(t *testing.T) {
+		loan, renewPath := checkout(t)
+
+		// Perpanjangan loan yang terlambat akan menghapus denda keterlambatannya
+		err := db.Model(&models.Loan{}).Where("id = ?", loan["id"]).Update("due_at", time.Now().Add(-72*time.Hour)).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectError(t, doRequest(t, app, http.MethodPost, renewPath, nil, member), http.StatusConflict, "loan_overdue")
+
+		resp := doRequest(t, app, http.MethodGet, fmt.Sprintf("/api/loans/%v", loan["id"]), nil, member)
+		if resp.data()["renewal_count"] != float64(0) || resp.data()["is_overdue"] != true {
+			t.Errorf("loan after rejected renewal = %v", resp.data())
+		}
+	})
+
+	t.Run("held by another member", func(t *testing.T) {
+		loan, renewPath := checkout(t)
+		other := createMember(t, db, "other")
+		holdPath := fmt.Sprintf("/api/books/%v/holds", loan["book_id"])
+		expectStatus(t, doRequest(t, app, http.MethodPost, holdPath, map[string]any{"user_id": other}, admin), http.StatusCreated)
+
+		expectError(t, doRequest(t, app, http.MethodPost, renewPath, nil, member), http.StatusConflict, "hold_pending")
+	})
+}
+
+// Hold hanya ada di route dengan database, jadi test antrean hold memakai SQLite saja
+func TestHoldQueue(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	admin := tokenFor(t, models.RoleAdmin)
+
+	book := createBook(t, app, withField(bookPayload(1), "copies", 1))
+	loan := doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"book_id": book["id"]}, tokenFor(t, models.RoleUser))
+	expectStatus(t, loan, http.StatusCreated)
+
+	first, second := createMember(t, db, "first"), createMember(t, db, "second")
+	for _, userID := range []uint{first, second} {
+		resp := doRequest(t, app, http.MethodPost, bookPath(book, "/holds"), map[string]any{"user_id": userID}, admin)
+		expectStatus(t, resp, http.StatusCreated)
+		if resp.data()["status"] != models.HoldStatusWaiting {
+			t.Errorf("hold for user %d status = %v, want waiting", userID, resp.data()["status"])
+		}
+	}
+
+	queue := func() (holds []map[string]any, length any) {
+		resp := doRequest(t, app, http.MethodGet, bookPath(book, "/holds"), nil, admin)
+		expectStatus(t, resp, http.StatusOK)
+		for _, item := range resp.list() {
+			holds = append(holds, item.(map[string]any))
+		}
+		return holds, resp.meta()["queue_length"]
+	}
+
+	t.Run("return readies only the first hold", func(t *testing.T) {
+		expectStatus(t, doRequest(t, app, http.MethodPost, fmt.Sprintf("/api/loans/%v/return", loan.data()["id"]), nil, admin), http.StatusOK)
+
+		holds, _ := queue()
+		if len(holds) != 2 {
+			t.Fatalf("queue = %v, want 2 holds", holds)
+		}
+		if holds[0]["user_id"] != float64(first) || holds[0]["status"] != models.HoldStatusReady || holds[0]["copy_id"] == nil {
+			t.Errorf("first hold = %v, want ready with a copy for user %d", holds[0], first)
+		}
+		if holds[1]["user_id"] != float64(second) || holds[1]["status"] != models.HoldStatusWaiting || holds[1]["position"] != float64(2) {
+			t.Errorf("second hold = %v, want waiting at position 2 for user %d", holds[1], second)
+		}
+
+		resp := doRequest(t, app, http.MethodGet, bookPath(book, ""), nil, "")
+		if resp.data()["available_copies"] != float64(0) {
+			t.Errorf("available_copies = %v, want 0 while the copy is on hold", resp.data()["available_copies"])
+		}
+	})
+
+	t.Run("expired hold passes the copy on", func(t *testing.T) {
+		holds, _ := queue()
+		if len(holds) != 2 {
+			t.Fatalf("queue = %v, want 2 holds", holds)
+		}
+		heldCopy := holds[0]["copy_id"]
+		err := db.Model(&models.Hold{}).Where("id = ?", holds[0]["id"]).Update("expires_at", time.Now().Add(-time.Minute)).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			ExpireHolds(ctx, repositories.NewHoldRepository(db), 10*time.Millisecond)
+			close(done)
+		}()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, length := queue(); length == float64(1) || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		cancel()
+		<-done
+
+		holds, length := queue()
+		if length != float64(1) || len(holds) != 1 {
+			t.Fatalf("queue = %v (length %v), want only the second hold", holds, length)
+		}
+		if holds[0]["user_id"] != float64(second) || holds[0]["status"] != models.HoldStatusReady || holds[0]["copy_id"] != heldCopy {
+			t.Errorf("second hold = %v, want ready with copy %v", holds[0], heldCopy)
+		}
+
+		var expired models.Hold
+		if err := db.Where("user_id = ?", first).First(&expired).Error; err != nil {
+			t.Fatal(err)
+		}
+		if expired.Status != models.HoldStatusExpired || expired.CopyID != nil {
+			t.Errorf("first hold status = %s copy = %v, want expired without a copy", expired.Status, expired.CopyID)
+		}
+	})
+}
+
+func TestFines(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	admin, member := tokenFor(t, models.RoleAdmin), tokenFor(t, models.RoleUser)
+	policy := config.Library.FinePolicy
+
+	// overdueLoan meminjam book untuk member lalu memundurkan jatuh temponya
+	overdueLoan := func(n int, overdue time.Duration) map[string]any {
+		t.Helper()
+		book := createBook(t, app, bookPayload(n))
+		loan := doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"book_id": book["id"]}, member)
+		expectStatus(t, loan, http.StatusCreated)
+		err := db.Model(&models.Loan{}).Where("id = ?", loan.data()["id"]).Update("due_at", time.Now().Add(-overdue)).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loan.data()
+	}
+	balance := func() map[string]any {
+		t.Helper()
+		resp := doRequest(t, app, http.MethodGet, "/api/fines/balance", nil, member)
+		expectStatus(t, resp, http.StatusOK)
+		return resp.data()
+	}
+
+	// Terlambat 2 hari lebih sedikit dihitung 3 hari
+	loan := overdueLoan(1, 49*time.Hour)
+	resp := doRequest(t, app, http.MethodPost, fmt.Sprintf("/api/loans/%v/return", loan["id"]), nil, admin)
+	expectStatus(t, resp, http.StatusOK)
+	fine, _ := resp.Body["fine"].(map[string]any)
+	wantAmount := float64(policy.Calculate(3))
+	if fine["days_overdue"] != float64(3) || fine["amount"] != wantAmount || fine["status"] != models.FineStatusOutstanding {
+		t.Fatalf("fine = %v, want 3 days, amount %v, outstanding", fine, wantAmount)
+	}
+	if got := balance(); got["outstanding"] != wantAmount || got["total"] != wantAmount {
+		t.Errorf("balance after late return = %v, want %v", got, wantAmount)
+	}
+
+	finePath := fmt.Sprintf("/api/fines/%v", fine["id"])
+	expectStatus(t, doRequest(t, app, http.MethodPost, finePath+"/payments", map[string]any{"amount": policy.DailyRate}, admin), http.StatusOK)
+	if got := balance(); got["outstanding"] != wantAmount-float64(policy.DailyRate) {
+		t.Errorf("balance after payment = %v, want %v", got, wantAmount-float64(policy.DailyRate))
+	}
+	expectError(t, doRequest(t, app, http.MethodPost, finePath+"/payments", map[string]any{"amount": int64(wantAmount)}, admin), http.StatusBadRequest, "amount_exceeds_fine")
+
+	// Waiver tanpa amount menghapus sisanya; fine yang sebagian dibayar tercatat paid
+	resp = doRequest(t, app, http.MethodPost, finePath+"/waive", nil, admin)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.data()["status"] != models.FineStatusPaid || len(resp.data()["transactions"].([]any)) != 2 {
+		t.Errorf("fine after waiver = %v, want paid with 2 transactions", resp.data())
+	}
+	if got := balance(); got["total"] != float64(0) {
+		t.Errorf("balance after waiver = %v, want 0", got)
+	}
+
+	// Denda berjalan dari pinjaman yang masih terlambat ikut dihitung untuk batas checkout
+	overdueDays := int(config.Library.FineBlockThreshold/policy.DailyRate) + 1
+	overdueLoan(2, time.Duration(overdueDays)*24*time.Hour)
+	if got := balance(); got["accruing"].(float64) <= float64(config.Library.FineBlockThreshold) {
+		t.Fatalf("balance = %v, want accruing above %d", got, config.Library.FineBlockThreshold)
+	}
+	book := createBook(t, app, bookPayload(3))
+	resp = doRequest(t, app, http.MethodPost, "/api/loans", map[string]any{"book_id": book["id"]}, member)
+	expectError(t, resp, http.StatusForbidden, "fine_limit_exceeded")
+}
+
+func TestRunWaitsForWorkersBeforeClosingDB(t *testing.T) {
+	dsn := config.SQLiteDSN(filepath.Join(t.TempDir(), "run.db"))
+	db, err := config.OpenDB(sqlite.Open(dsn), logger.Silent)
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+
+	// Worker yang masih memakai database sebentar setelah ctx selesai, seperti ExpireHolds di
+	// tengah transaksi
+	workerErr := make(chan error, 1)
+	worker := func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(100 * time.Millisecond)
+		workerErr <- db.Exec("SELECT 1").Error
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, New(Config{DB: db}), "127.0.0.1:0", db, time.Second, worker)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after context was cancelled")
+	}
+
+	select {
+	case err := <-workerErr:
+		if err != nil {
+			t.Errorf("worker query failed: %v", err)
+		}
+	default:
+		t.Error("Run returned before the worker finished")
+	}
+}
+
+func TestFuzzyVocabularyQuery(t *testing.T) {
+	db := newSQLiteDB(t)
+	app := New(Config{DB: db})
+	createBook(t, app, withField(withField(bookPayload(1), "title", "Animal Farm"), "author", "George Orwell"))
+
+	// Mencatat query vocabulary koreksi salah ketik, satu-satunya yang menyaring field
+	var vocabulary []string
+	err := db.Callback().Query().After("gorm:query").Register("test:vocabulary", func(tx *gorm.DB) {
+		if sql := tx.Statement.SQL.String(); strings.Contains(sql, "field IN") {
+			vocabulary = append(vocabulary, sql)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	search := func(query string) int {
+		resp := doRequest(t, app, http.MethodGet, "/api/books?search="+url.QueryEscape(query), nil, "")
+		expectStatus(t, resp, http.StatusOK)
+		return len(resp.list())
+	}
+
+	if got := search("fam george"); got != 0 || len(vocabulary) != 0 {
+		t.Errorf("short missing word: items = %d, vocabulary queries = %d, want 0 and 0", got, len(vocabulary))
+	}
+	if got := search("orwel farm"); got != 1 || len(vocabulary) != 1 {
+		t.Errorf("long missing word: items = %d, vocabulary queries = %d, want 1 and 1", got, len(vocabulary))
+	}
+	if len(vocabulary) == 1 && !strings.Contains(vocabulary[0], "LENGTH(term) BETWEEN") {
+		t.Errorf("vocabulary query not narrowed: %s", vocabulary[0])
+	}
+}
@@ -0,0 +1,121 @@
+// Package validation menjalankan aturan di struct tag `validate` pada request body dan
+// mengembalikan pelanggarannya per field sebagai error 422.
+package validation
+
+import (
+	"backend_perpustakaan_online/apperror"
+	"backend_perpustakaan_online/isbn"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrInvalid adalah error dasar kegagalan validasi, Details berisi []FieldError
+var ErrInvalid = apperror.Unprocessable("validation_failed", "Data tidak valid")
+
+// FieldError adalah satu pelanggaran aturan validasi. Field memakai nama JSON.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Nama field di pesan error mengikuti tag json supaya sama dengan yang dikirim klien
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// isbn bawaan validator hanya membuang tiga tanda hubung, jadi ISBN-13 berformat lengkap ditolak
+	v.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		return isbn.Valid(fl.Field().String())
+	})
+	v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+		date, ok := fl.Field().Interface().(time.Time)
+		return ok && !date.After(time.Now())
+	})
+
+	return v
+}
+
+// Struct memvalidasi semua field s
+func Struct(s any) error {
+	return translate(validate.Struct(s))
+}
+
+// Provided hanya memvalidasi field s yang tidak bernilai nol, untuk update sebagian di mana field
+// kosong berarti tidak diubah
+func Provided(s any) error {
+	val := reflect.Indirect(reflect.ValueOf(s))
+	typ := val.Type()
+
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).IsExported() && !val.Field(i).IsZero() {
+			fields = append(fields, typ.Field(i).Name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return translate(validate.StructPartial(s, fields...))
+}
+
+func translate(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	details := make([]FieldError, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		// Namespace diawali nama struct, misalnya BookRequest.title, kecuali untuk struct anonim
+		field := fe.Namespace()
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest
+		}
+		details = append(details, FieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: field + " " + message(fe),
+		})
+	}
+	return ErrInvalid.WithDetails(details)
+}
+
+func message(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "wajib diisi"
+	case "email":
+		return "harus berupa alamat email yang valid"
+	case "oneof":
+		return "harus salah satu dari: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "isbn":
+		return "harus berupa ISBN-10 atau ISBN-13 yang valid"
+	case "notfuture":
+		return "tidak boleh di masa depan"
+	case "min", "max":
+		bound := "minimal"
+		if fe.Tag() == "max" {
+			bound = "maksimal"
+		}
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("harus %s %s karakter", bound, fe.Param())
+		}
+		return fmt.Sprintf("harus %s %s", bound, fe.Param())
+	}
+	return "tidak valid"
+}